@@ -4,6 +4,23 @@ package constant
 // HeaderTraceID 机器人openapi返回的链路追踪ID
 const HeaderTraceID = "X-Tps-trace-ID"
 
+// HeaderBotAppID http 回调请求中携带的机器人 AppID
+const HeaderBotAppID = "X-Bot-Appid"
+
+// 限频相关的响应头，用于计算重试前需要等待的时间
+const (
+	// HeaderRetryAfter 标准的 Retry-After，值为秒数或者 http 时间
+	HeaderRetryAfter = "Retry-After"
+	// HeaderRateLimitResetAfter 距离限频重置的秒数，可以为小数
+	HeaderRateLimitResetAfter = "X-RateLimit-Reset-After"
+	// HeaderRateLimitReset 限频重置的 unix 时间戳，单位秒
+	HeaderRateLimitReset = "X-RateLimit-Reset"
+	// HeaderRateLimitLimit 限频周期内允许的请求数
+	HeaderRateLimitLimit = "X-RateLimit-Limit"
+	// HeaderRateLimitRemaining 限频周期内剩余的请求数
+	HeaderRateLimitRemaining = "X-RateLimit-Remaining"
+)
+
 // APIDomain api domain
 var APIDomain = "https://api.sgroup.qq.com"
 
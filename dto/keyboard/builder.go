@@ -0,0 +1,184 @@
+package keyboard
+
+// 按钮样式
+const (
+	StyleGrayLine  = 0 // 灰色线框
+	StyleBlueLine  = 1 // 蓝色线框
+	StyleRed       = 3 // 白色背景，红色字体
+	StyleBlueSolid = 4 // 蓝色背景，白色字体
+)
+
+// Builder 自定义按钮组件的构造器，Build 时校验平台的限制
+//
+//	kb, err := keyboard.NewBuilder().
+//		Row(keyboard.CallbackButton("like", "赞", "like").Style(keyboard.StyleBlueLine)).
+//		Row(keyboard.URLButton("doc", "文档", "https://bot.q.qq.com/wiki")).
+//		Build()
+type Builder struct {
+	keyboard CustomKeyboard
+}
+
+// NewBuilder 创建自定义按钮组件的构造器
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// Row 添加一行按钮
+func (b *Builder) Row(buttons ...*ButtonBuilder) *Builder {
+	row := &Row{Buttons: make([]*Button, 0, len(buttons))}
+	for _, button := range buttons {
+		row.Buttons = append(row.Buttons, button.Button())
+	}
+	b.keyboard.Rows = append(b.keyboard.Rows, row)
+	return b
+}
+
+// FontSize 设置按钮的字体大小
+func (b *Builder) FontSize(size string) *Builder {
+	b.keyboard.Style = &KeyboardStyle{FontSize: size}
+	return b
+}
+
+// Build 生成消息按钮组件，不符合平台的限制时返回错误
+func (b *Builder) Build() (*MessageKeyboard, error) {
+	content := b.keyboard
+	kb := &MessageKeyboard{Content: &content}
+	if err := kb.Validate(); err != nil {
+		return nil, err
+	}
+	return kb, nil
+}
+
+// ButtonBuilder 单个按钮的构造器，默认所有人可以操作
+type ButtonBuilder struct {
+	button Button
+}
+
+func newButton(id, label string, actionType ActionType, data string) *ButtonBuilder {
+	return &ButtonBuilder{button: Button{
+		ID:         id,
+		RenderData: &RenderData{Label: label, VisitedLabel: label},
+		Action: &Action{
+			Type:       actionType,
+			Data:       data,
+			Permission: &Permission{Type: PermissionTypAll},
+		},
+	}}
+}
+
+// URLButton 跳转链接的按钮
+func URLButton(id, label, url string) *ButtonBuilder {
+	return newButton(id, label, ActionTypeURL, url)
+}
+
+// CallbackButton 回调按钮，点击后 data 通过互动事件回调给机器人
+func CallbackButton(id, label, data string) *ButtonBuilder {
+	return newButton(id, label, ActionTypeCallback, data)
+}
+
+// CommandButton 指令按钮，点击后在输入框中 @机器人 并填入 command
+func CommandButton(id, label, command string) *ButtonBuilder {
+	return newButton(id, label, ActionTypeAtBot, command)
+}
+
+// SubscribeButton 订阅按钮，templates 为订阅的模板
+func SubscribeButton(id, label string, templates ...*TemplateID) *ButtonBuilder {
+	b := newButton(id, label, ActionTypeSubscribe, "")
+	b.button.Action.SubscribeData = SubscribeData{TemplateIDs: templates}
+	return b
+}
+
+// VisitedLabel 设置点击后按钮上的文字，默认与 label 相同
+func (b *ButtonBuilder) VisitedLabel(label string) *ButtonBuilder {
+	b.button.RenderData.VisitedLabel = label
+	return b
+}
+
+// Style 设置按钮样式
+func (b *ButtonBuilder) Style(style int) *ButtonBuilder {
+	b.button.RenderData.Style = style
+	return b
+}
+
+// Enter 指令按钮点击后直接发送，不需要用户确认
+func (b *ButtonBuilder) Enter() *ButtonBuilder {
+	b.button.Action.Enter = true
+	return b
+}
+
+// ShowChannelList 指令按钮点击后弹出子频道选择器
+func (b *ButtonBuilder) ShowChannelList() *ButtonBuilder {
+	b.button.Action.AtBotShowChannelList = true
+	return b
+}
+
+// ClickLimit 设置可以点击的次数
+func (b *ButtonBuilder) ClickLimit(n uint32) *ButtonBuilder {
+	b.button.Action.ClickLimit = n
+	return b
+}
+
+// Group 设置分组，同一分组内有一个按钮操作后，其他按钮不可点击，只对回调按钮有效
+func (b *ButtonBuilder) Group(id string) *ButtonBuilder {
+	b.button.GroupID = id
+	return b
+}
+
+// Confirm 点击后需要二次确认，confirm 与 cancel 为空时使用平台默认的文字
+func (b *ButtonBuilder) Confirm(content, confirm, cancel string) *ButtonBuilder {
+	b.button.Action.Modal = &Modal{Content: content, ConfirmText: confirm, CancelText: cancel}
+	return b
+}
+
+// ForUsers 仅指定的用户可以操作
+func (b *ButtonBuilder) ForUsers(userIDs ...string) *ButtonBuilder {
+	b.button.Action.Permission = &Permission{Type: PermissionTypeSpecifyUserIDs, SpecifyUserIDs: userIDs}
+	return b
+}
+
+// ForRoles 仅指定身份组的成员可以操作
+func (b *ButtonBuilder) ForRoles(roleIDs ...string) *ButtonBuilder {
+	b.button.Action.Permission = &Permission{Type: PermissionTypSpecifyRoleIDs, SpecifyRoleIDs: roleIDs}
+	return b
+}
+
+// ForManager 仅管理者可以操作
+func (b *ButtonBuilder) ForManager() *ButtonBuilder {
+	b.button.Action.Permission = &Permission{Type: PermissionTypManager}
+	return b
+}
+
+// Button 生成按钮，每次调用都返回新的深拷贝，之后继续修改 builder 不会影响已经生成的按钮
+func (b *ButtonBuilder) Button() *Button {
+	button := b.button
+	renderData, action := *b.button.RenderData, *b.button.Action
+	if p := action.Permission; p != nil {
+		action.Permission = &Permission{
+			Type:           p.Type,
+			SpecifyRoleIDs: copyStrings(p.SpecifyRoleIDs),
+			SpecifyUserIDs: copyStrings(p.SpecifyUserIDs),
+		}
+	}
+	if m := action.Modal; m != nil {
+		modal := *m
+		action.Modal = &modal
+	}
+	if ids := action.SubscribeData.TemplateIDs; ids != nil {
+		action.SubscribeData.TemplateIDs = make([]*TemplateID, len(ids))
+		for i, id := range ids {
+			if id != nil {
+				templateID := *id
+				action.SubscribeData.TemplateIDs[i] = &templateID
+			}
+		}
+	}
+	button.RenderData, button.Action = &renderData, &action
+	return &button
+}
+
+func copyStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append(make([]string, 0, len(s)), s...)
+}
@@ -0,0 +1,95 @@
+package keyboard
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tencent-connect/botgo/errs"
+)
+
+func TestBuilder(t *testing.T) {
+	t.Run("build", func(t *testing.T) {
+		kb, err := NewBuilder().
+			Row(CallbackButton("like", "赞", "like").Style(StyleBlueLine).Group("vote"),
+				CallbackButton("dislike", "踩", "dislike").Group("vote").ForUsers("u1")).
+			Row(URLButton("doc", "文档", "https://bot.q.qq.com/wiki").Confirm("确认打开文档吗", "", "")).
+			Build()
+		assert.Nil(t, err)
+		assert.Len(t, kb.Content.Rows, 2)
+		button := kb.Content.Rows[0].Buttons[1]
+		assert.Equal(t, "踩", button.RenderData.VisitedLabel)
+		assert.Equal(t, ActionTypeCallback, button.Action.Type)
+		assert.Equal(t, []string{"u1"}, button.Action.Permission.SpecifyUserIDs)
+		assert.Equal(t, PermissionTypAll, kb.Content.Rows[0].Buttons[0].Action.Permission.Type)
+	})
+	t.Run("validate", func(t *testing.T) {
+		tooMany := NewBuilder()
+		for i := 0; i <= MaxRows; i++ {
+			tooMany.Row(CallbackButton("", "b", "d"))
+		}
+		cases := []struct {
+			name    string
+			builder *Builder
+			field   string
+		}{
+			{"no rows", NewBuilder(), "keyboard.content.rows"},
+			{"too many rows", tooMany, "keyboard.content.rows"},
+			{"too many buttons", NewBuilder().Row(CallbackButton("1", "1", ""), CallbackButton("2", "2", ""),
+				CallbackButton("3", "3", ""), CallbackButton("4", "4", ""), CallbackButton("5", "5", ""),
+				CallbackButton("6", "6", "")), "keyboard.content.rows[0].buttons"},
+			{"empty label", NewBuilder().Row(CallbackButton("1", "", "")),
+				"keyboard.content.rows[0].buttons[0].render_data.label"},
+			{"long label", NewBuilder().Row(CallbackButton("1", strings.Repeat("长", MaxLabelLength+1), "")),
+				"keyboard.content.rows[0].buttons[0].render_data.label"},
+			{"callback data", NewBuilder().Row(CallbackButton("1", "1", strings.Repeat("a", 129))),
+				"keyboard.content.rows[0].buttons[0].action.data"},
+			{"modal content", NewBuilder().Row(CallbackButton("1", "1", "").Confirm(strings.Repeat("确", 41), "", "")),
+				"keyboard.content.rows[0].buttons[0].action.modal.content"},
+			{"modal url", NewBuilder().Row(CallbackButton("1", "1", "").Confirm("https://qq.com", "", "")),
+				"keyboard.content.rows[0].buttons[0].action.modal.content"},
+			{"modal confirm", NewBuilder().Row(CallbackButton("1", "1", "").Confirm("确认", "确认确认确认", "")),
+				"keyboard.content.rows[0].buttons[0].action.modal.confirm_text"},
+			{"url group", NewBuilder().Row(URLButton("1", "1", "https://qq.com").Group("g")),
+				"keyboard.content.rows[0].buttons[0].group_id"},
+			{"no users", NewBuilder().Row(CallbackButton("1", "1", "").ForUsers()),
+				"keyboard.content.rows[0].buttons[0].action.permission.specify_user_ids"},
+			{"duplicate id", NewBuilder().Row(CallbackButton("1", "1", "")).Row(CallbackButton("1", "2", "")),
+				"keyboard.content.rows[1].buttons[0].id"},
+		}
+		for _, c := range cases {
+			t.Run(c.name, func(t *testing.T) {
+				_, err := c.builder.Build()
+				assert.True(t, errors.Is(err, errs.ErrInvalidPayload))
+				assert.Contains(t, err.Error(), c.field+":")
+			})
+		}
+	})
+	t.Run("button copy", func(t *testing.T) {
+		userIDs := []string{"u1"}
+		b := CallbackButton("like", "赞", "like").ForUsers(userIDs...).Confirm("确认吗", "", "")
+		first := b.Button()
+		// 修改传入的参数与已经生成的按钮，不影响其他按钮
+		userIDs[0] = "u2"
+		first.Action.Modal.Content = "changed"
+		assert.Equal(t, []string{"u1"}, first.Action.Permission.SpecifyUserIDs)
+		first.Action.Permission.SpecifyUserIDs[0] = "u3"
+		second := b.Button()
+		assert.Equal(t, []string{"u2"}, second.Action.Permission.SpecifyUserIDs)
+		assert.Equal(t, "确认吗", second.Action.Modal.Content)
+		// 继续修改 builder，不影响已经生成的按钮
+		b.ForManager().Style(StyleBlueSolid)
+		assert.Equal(t, PermissionTypeSpecifyUserIDs, second.Action.Permission.Type)
+		assert.NotEqual(t, StyleBlueSolid, second.RenderData.Style)
+
+		template := &TemplateID{TemplateID: 1}
+		subscribe := SubscribeButton("sub", "订阅", template).Button()
+		template.TemplateID = 2
+		assert.Equal(t, uint32(1), subscribe.Action.SubscribeData.TemplateIDs[0].TemplateID)
+	})
+	t.Run("keyboard id", func(t *testing.T) {
+		assert.Nil(t, (&MessageKeyboard{ID: "template"}).Validate())
+		assert.NotNil(t, (&MessageKeyboard{}).Validate())
+	})
+}
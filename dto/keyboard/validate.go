@@ -0,0 +1,146 @@
+package keyboard
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tencent-connect/botgo/errs"
+)
+
+// 平台对自定义按钮的限制
+const (
+	MaxRows                  = 5   // 最多的行数
+	MaxButtonsPerRow         = 5   // 每行最多的按钮数
+	MaxLabelLength           = 30  // 按钮文字的最大字符数
+	MaxCallbackDataBytes     = 128 // 回调按钮 data 的最大字节数
+	MaxModalContentLength    = 40  // 二次确认提示文本的最大字符数
+	MaxModalButtonTextLength = 4   // 二次确认按钮文字的最大字符数
+)
+
+// Validate 校验按钮组件是否符合平台的限制，返回的错误可以通过 errors.Is(err, errs.ErrInvalidPayload) 判断
+func (k *MessageKeyboard) Validate() error {
+	if k.ID != "" && k.Content != nil {
+		return errs.Invalid("keyboard", "id and content are mutually exclusive")
+	}
+	if k.ID == "" && k.Content == nil {
+		return errs.Invalid("keyboard", "id or content is required")
+	}
+	if k.Content != nil {
+		return k.Content.validate("keyboard.content")
+	}
+	return nil
+}
+
+// Validate 校验自定义按钮是否符合平台的限制
+func (k *CustomKeyboard) Validate() error {
+	return k.validate("content")
+}
+
+func (k *CustomKeyboard) validate(path string) error {
+	if len(k.Rows) == 0 {
+		return errs.Invalid(path+".rows", "at least one row is required")
+	}
+	if len(k.Rows) > MaxRows {
+		return errs.Invalid(path+".rows", "%d rows exceeds the limit of %d", len(k.Rows), MaxRows)
+	}
+	ids := make(map[string]string)
+	for i, row := range k.Rows {
+		rowPath := fmt.Sprintf("%s.rows[%d]", path, i)
+		if row == nil || len(row.Buttons) == 0 {
+			return errs.Invalid(rowPath, "at least one button is required")
+		}
+		if len(row.Buttons) > MaxButtonsPerRow {
+			return errs.Invalid(rowPath+".buttons", "%d buttons exceeds the limit of %d",
+				len(row.Buttons), MaxButtonsPerRow)
+		}
+		for j, button := range row.Buttons {
+			buttonPath := fmt.Sprintf("%s.buttons[%d]", rowPath, j)
+			if button == nil {
+				return errs.Invalid(buttonPath, "button is nil")
+			}
+			if err := button.validate(buttonPath); err != nil {
+				return err
+			}
+			if button.ID == "" {
+				continue
+			}
+			if prev, ok := ids[button.ID]; ok {
+				return errs.Invalid(buttonPath+".id", "duplicate id %q, already used by %s", button.ID, prev)
+			}
+			ids[button.ID] = buttonPath
+		}
+	}
+	return nil
+}
+
+// Validate 校验单个按钮是否符合平台的限制
+func (b *Button) Validate() error {
+	return b.validate("button")
+}
+
+func (b *Button) validate(path string) error {
+	if b.RenderData == nil || b.RenderData.Label == "" {
+		return errs.Invalid(path+".render_data.label", "label is required")
+	}
+	if n := utf8.RuneCountInString(b.RenderData.Label); n > MaxLabelLength {
+		return errs.Invalid(path+".render_data.label", "%d characters exceeds the limit of %d", n, MaxLabelLength)
+	}
+	if n := utf8.RuneCountInString(b.RenderData.VisitedLabel); n > MaxLabelLength {
+		return errs.Invalid(path+".render_data.visited_label", "%d characters exceeds the limit of %d",
+			n, MaxLabelLength)
+	}
+	if b.Action == nil {
+		return errs.Invalid(path+".action", "action is required")
+	}
+	if b.GroupID != "" && b.Action.Type != ActionTypeCallback {
+		return errs.Invalid(path+".group_id", "only callback button can be grouped")
+	}
+	return b.Action.validate(path + ".action")
+}
+
+func (a *Action) validate(path string) error {
+	switch a.Type {
+	case ActionTypeURL, ActionTypeMQQAPI:
+		if a.Data == "" {
+			return errs.Invalid(path+".data", "link is required")
+		}
+	case ActionTypeCallback:
+		if len(a.Data) > MaxCallbackDataBytes {
+			return errs.Invalid(path+".data", "%d bytes exceeds the limit of %d", len(a.Data), MaxCallbackDataBytes)
+		}
+	case ActionTypeSubscribe:
+		if len(a.SubscribeData.TemplateIDs) == 0 {
+			return errs.Invalid(path+".subscribe_data.template_ids", "at least one template is required")
+		}
+	}
+	if a.Permission == nil {
+		return errs.Invalid(path+".permission", "permission is required")
+	}
+	switch {
+	case a.Permission.Type == PermissionTypeSpecifyUserIDs && len(a.Permission.SpecifyUserIDs) == 0:
+		return errs.Invalid(path+".permission.specify_user_ids", "user ids are required")
+	case a.Permission.Type == PermissionTypSpecifyRoleIDs && len(a.Permission.SpecifyRoleIDs) == 0:
+		return errs.Invalid(path+".permission.specify_role_ids", "role ids are required")
+	}
+	if a.Modal != nil {
+		return a.Modal.validate(path + ".modal")
+	}
+	return nil
+}
+
+func (m *Modal) validate(path string) error {
+	if n := utf8.RuneCountInString(m.Content); n > MaxModalContentLength {
+		return errs.Invalid(path+".content", "%d characters exceeds the limit of %d", n, MaxModalContentLength)
+	}
+	if strings.Contains(m.Content, "://") {
+		return errs.Invalid(path+".content", "url is not allowed")
+	}
+	if n := utf8.RuneCountInString(m.ConfirmText); n > MaxModalButtonTextLength {
+		return errs.Invalid(path+".confirm_text", "%d characters exceeds the limit of %d", n, MaxModalButtonTextLength)
+	}
+	if n := utf8.RuneCountInString(m.CancelText); n > MaxModalButtonTextLength {
+		return errs.Invalid(path+".cancel_text", "%d characters exceeds the limit of %d", n, MaxModalButtonTextLength)
+	}
+	return nil
+}
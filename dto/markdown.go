@@ -0,0 +1,99 @@
+package dto
+
+import (
+	"fmt"
+
+	"github.com/tencent-connect/botgo/errs"
+)
+
+// markdown 字体大小
+const (
+	MarkdownFontSmall  = "small"
+	MarkdownFontMiddle = "middle"
+	MarkdownFontLarge  = "large"
+)
+
+// MarkdownBuilder markdown 消息的构造器，Build 时校验平台的限制
+//
+//	md, err := dto.NewMarkdownTemplate("101993071_1658748972").
+//		Param("title", "标题").
+//		Param("content", "第一行", "第二行").
+//		Build()
+type MarkdownBuilder struct {
+	markdown Markdown
+}
+
+// NewMarkdown 使用原生 markdown 内容
+func NewMarkdown(content string) *MarkdownBuilder {
+	return &MarkdownBuilder{markdown: Markdown{Content: content}}
+}
+
+// NewMarkdownTemplate 使用自定义模板
+func NewMarkdownTemplate(customTemplateID string) *MarkdownBuilder {
+	return &MarkdownBuilder{markdown: Markdown{CustomTemplateID: customTemplateID}}
+}
+
+// Param 设置模板参数，只对模板生效
+func (b *MarkdownBuilder) Param(key string, values ...string) *MarkdownBuilder {
+	b.markdown.Params = append(b.markdown.Params, &MarkdownParams{Key: key, Values: values})
+	return b
+}
+
+// Style 设置 markdown 样式
+func (b *MarkdownBuilder) Style(fontSize, layout string) *MarkdownBuilder {
+	b.markdown.Style = &MarkdownStyle{MainFontSize: fontSize, Layout: layout}
+	return b
+}
+
+// ProcessMsg 设置引导消息
+func (b *MarkdownBuilder) ProcessMsg(msg string) *MarkdownBuilder {
+	b.markdown.ProcessMsg = msg
+	return b
+}
+
+// Build 生成 markdown 消息，不符合平台的限制时返回错误
+func (b *MarkdownBuilder) Build() (*Markdown, error) {
+	md := b.markdown
+	md.Params = append([]*MarkdownParams(nil), b.markdown.Params...)
+	if err := md.Validate(); err != nil {
+		return nil, err
+	}
+	return &md, nil
+}
+
+// Validate 校验 markdown 消息是否符合平台的限制，返回的错误可以通过 errors.Is(err, errs.ErrInvalidPayload) 判断
+func (m *Markdown) Validate() error {
+	isTemplate := m.TemplateID != 0 || m.CustomTemplateID != ""
+	switch {
+	case m.TemplateID != 0 && m.CustomTemplateID != "":
+		return errs.Invalid("markdown", "template_id and custom_template_id are mutually exclusive")
+	case isTemplate && m.Content != "":
+		return errs.Invalid("markdown", "template and content are mutually exclusive")
+	case !isTemplate && m.Content == "":
+		return errs.Invalid("markdown", "template or content is required")
+	case !isTemplate && len(m.Params) > 0:
+		return errs.Invalid("markdown.params", "params are only allowed with template")
+	}
+	keys := make(map[string]bool, len(m.Params))
+	for i, param := range m.Params {
+		path := fmt.Sprintf("markdown.params[%d]", i)
+		if param == nil || param.Key == "" {
+			return errs.Invalid(path+".key", "key is required")
+		}
+		if keys[param.Key] {
+			return errs.Invalid(path+".key", "duplicate key %q", param.Key)
+		}
+		keys[param.Key] = true
+		if len(param.Values) == 0 {
+			return errs.Invalid(path+".values", "at least one value is required")
+		}
+	}
+	if m.Style != nil {
+		switch m.Style.MainFontSize {
+		case "", MarkdownFontSmall, MarkdownFontMiddle, MarkdownFontLarge:
+		default:
+			return errs.Invalid("markdown.style.main_font_size", "unknown font size %q", m.Style.MainFontSize)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package dto
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tencent-connect/botgo/errs"
+)
+
+func TestMarkdownBuilder(t *testing.T) {
+	t.Run("template", func(t *testing.T) {
+		md, err := NewMarkdownTemplate("tpl").Param("title", "标题").Style(MarkdownFontLarge, "").Build()
+		assert.Nil(t, err)
+		assert.Equal(t, "tpl", md.CustomTemplateID)
+		assert.Equal(t, []string{"标题"}, md.Params[0].Values)
+	})
+	t.Run("validate", func(t *testing.T) {
+		cases := map[string]*MarkdownBuilder{
+			"empty":         NewMarkdown(""),
+			"content param": NewMarkdown("# hi").Param("title", "标题"),
+			"duplicate key": NewMarkdownTemplate("tpl").Param("title", "a").Param("title", "b"),
+			"no values":     NewMarkdownTemplate("tpl").Param("title"),
+			"font size":     NewMarkdown("# hi").Style("huge", ""),
+		}
+		for name, b := range cases {
+			_, err := b.Build()
+			assert.True(t, errors.Is(err, errs.ErrInvalidPayload), name)
+		}
+		md := &Markdown{TemplateID: 1, Content: "# hi"}
+		assert.True(t, errors.Is(md.Validate(), errs.ErrInvalidPayload))
+	})
+}
@@ -1,6 +1,12 @@
 package dto
 
-import "github.com/tencent-connect/botgo/dto/keyboard"
+import (
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+
+	"github.com/tencent-connect/botgo/dto/keyboard"
+)
 
 // SendType 消息类型
 type SendType int
@@ -22,11 +28,34 @@ type RichMediaMessage struct {
 	EventID    string `json:"event_id,omitempty"`     // 已经废弃：要回复的事件id, 逻辑同MsgID
 	FileType   uint64 `json:"file_type,omitempty"`    // 业务类型，图片，文件，语音，视频 文件类型，取值:1图片,2视频,3语音(目前语音只支持silk格式)
 	URL        string `json:"url,omitempty"`          // 需发送的富媒体文件，HTTP或者HTTPS链接
+	FileData   string `json:"file_data,omitempty"`    // 需发送的富媒体文件的 base64 编码，与 URL 二选一
 	SrvSendMsg bool   `json:"srv_send_msg,omitempty"` // 为true时会直接发送到群/C2C，且会占用主动消息频率, 为false为上传富媒体文件
 	Content    string `json:"content,omitempty"`
 	MsgSeq     int64  `json:"msg_seq,omitempty"` // 机器人对于回复一个msg_id或者event_id的消息序号，指定后根据这个字段和msg_id或者event_id进行去重
 }
 
+// 富媒体文件类型
+const (
+	RichMediaImage uint64 = 1 // 图片，png/jpg
+	RichMediaVideo uint64 = 2 // 视频，mp4
+	RichMediaVoice uint64 = 3 // 语音，silk
+	RichMediaFile  uint64 = 4 // 文件
+)
+
+// NewRichMediaFromURL 使用文件链接创建用于上传的富媒体消息
+func NewRichMediaFromURL(fileType uint64, url string) *RichMediaMessage {
+	return &RichMediaMessage{FileType: fileType, URL: url}
+}
+
+// NewRichMediaFromReader 读取本地文件内容，创建用于上传的富媒体消息，文件内容会以 base64 编码上传
+func NewRichMediaFromReader(fileType uint64, r io.Reader) (*RichMediaMessage, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return &RichMediaMessage{FileType: fileType, FileData: base64.StdEncoding.EncodeToString(data)}, nil
+}
+
 // GetEventID 事件ID
 func (msg RichMediaMessage) GetEventID() string {
 	return ""
@@ -82,6 +111,14 @@ type Stream struct {
 	Reset bool   `json:"reset,omitempty"` // 重新生成流式消息标记，此参数只能使用于流式消息分片还没有发送完成时，reset时Index需要从0开始，需要填写流式ID。
 }
 
+// 流式消息状态
+const (
+	StreamStateGenerating      int32 = 1  // 正文生成中
+	StreamStateDone            int32 = 10 // 正文生成结束
+	StreamStateGuideGenerating int32 = 11 // 引导消息生成中
+	StreamStateGuideDone       int32 = 20 // 引导消息生成结束
+)
+
 // PromptKeyboard 交互区操作
 type PromptKeyboard struct {
 	Keyboard *keyboard.MessageKeyboard `json:"keyboard,omitempty"` // 消息按钮组件
@@ -167,3 +204,16 @@ type InputNotify struct {
 type MediaInfo struct {
 	FileInfo []byte `json:"file_info,omitempty"` // 富媒体文件信息，通过上传接口取得
 }
+
+// MediaFile 上传富媒体文件的结果
+type MediaFile struct {
+	FileUUID string `json:"file_uuid"`
+	FileInfo []byte `json:"file_info"` // 富媒体文件信息，用于发送富媒体消息
+	TTL      uint   `json:"ttl"`       // 有效期，单位秒，有效期内可以重复使用
+	ID       string `json:"id,omitempty"`
+}
+
+// MediaInfo 获取用于发送富媒体消息的 MediaInfo，配合 RichMediaMsg 类型的消息使用
+func (f *MediaFile) MediaInfo() *MediaInfo {
+	return &MediaInfo{FileInfo: f.FileInfo}
+}
@@ -45,6 +45,42 @@ func (s *Session) String() string {
 		s.ID, s.Shards.ShardID, s.Shards.ShardCount, s.Intent)
 }
 
+// Record 获取 session 可以序列化的描述，不包含 TokenSource
+func (s *Session) Record() SessionRecord {
+	return SessionRecord{
+		ID:      s.ID,
+		URL:     s.URL,
+		Intent:  s.Intent,
+		LastSeq: s.LastSeq,
+		Shards:  s.Shards,
+		AppID:   s.AppID,
+	}
+}
+
+// SessionRecord session 可以序列化的描述，用于在 redis 等外部存储中传递 session
+// TokenSource 是接口，无法序列化，需要在使用方根据 AppID 重新创建
+type SessionRecord struct {
+	ID      string      `json:"id"`
+	URL     string      `json:"url"`
+	Intent  Intent      `json:"intent"`
+	LastSeq uint32      `json:"last_seq"`
+	Shards  ShardConfig `json:"shards"`
+	AppID   string      `json:"app_id"`
+}
+
+// Session 使用 tokenSource 还原 session
+func (r SessionRecord) Session(tokenSource oauth2.TokenSource) Session {
+	return Session{
+		ID:          r.ID,
+		URL:         r.URL,
+		TokenSource: tokenSource,
+		Intent:      r.Intent,
+		LastSeq:     r.LastSeq,
+		Shards:      r.Shards,
+		AppID:       r.AppID,
+	}
+}
+
 // WSUser 当前连接的用户信息
 type WSUser struct {
 	ID       string `json:"id"`
@@ -0,0 +1,138 @@
+package errs
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// openapi 错误的分类，openapi 返回的错误会包装对应的分类，可以使用 errors.Is 判断，如
+//
+//	if errors.Is(err, errs.ErrNoPermission) { ... }
+//
+// 需要获取 http 状态码，平台错误码等详细信息时，使用 errors.As 获取 *Err
+var (
+	// ErrUnauthorized 鉴权失败，token 无效或者过期
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrNoPermission 没有权限调用接口或者操作对应的资源
+	ErrNoPermission = errors.New("no permission")
+	// ErrNotFound 资源不存在
+	ErrNotFound = errors.New("not found")
+	// ErrMessageAudit 消息需要审核或者审核不通过
+	ErrMessageAudit = errors.New("message audit")
+	// ErrReplyExpired 被动回复的窗口已经过期，或者回复次数已经用完
+	ErrReplyExpired = errors.New("passive reply expired")
+	// ErrServerError 平台内部错误，一般可以重试
+	ErrServerError = errors.New("server error")
+)
+
+// openapi 错误码
+const (
+	APICodeMessageFrequencyLimit = 22009    // 消息发送超频
+	APICodePushMessageAudit      = 304023   // 主动消息需要审核
+	APICodeReplyMessageAudit     = 304024   // 回复消息需要审核
+	APICodeReplyExpired          = 40034024 // 被动回复的消息 ID 或者事件 ID 已经过期
+	APICodeReplyExhausted        = 40034025 // 被动回复的次数已经用完
+)
+
+var (
+	apiCodeCategoryLock sync.RWMutex
+	// apiCodeCategory 平台错误码对应的错误分类，优先于 http 状态码
+	apiCodeCategory = map[int]error{
+		APICodeTokenExpireOrNotExist: ErrUnauthorized,
+		APICodeMessageFrequencyLimit: ErrRateLimited,
+		APICodePushMessageAudit:      ErrMessageAudit,
+		APICodeReplyMessageAudit:     ErrMessageAudit,
+		APICodeReplyExpired:          ErrReplyExpired,
+		APICodeReplyExhausted:        ErrReplyExpired,
+	}
+)
+
+// RegisterAPICode 注册平台错误码对应的错误分类，用于 sdk 没有及时跟进的错误码
+func RegisterAPICode(code int, category error) {
+	apiCodeCategoryLock.Lock()
+	defer apiCodeCategoryLock.Unlock()
+	apiCodeCategory[code] = category
+}
+
+// apiErrBody openapi 请求出错情况下的 body 结构
+type apiErrBody struct {
+	Message string `json:"message"`  // 错误原因
+	Code    int    `json:"code"`     // 错误码，后续废弃
+	ErrCode int    `json:"err_code"` // 错误码
+	TraceID string `json:"trace_id"` // 服务端traceID, 用于问题排查
+}
+
+// NewAPIError 根据 openapi 的响应创建错误，错误码为 http 状态码，text 为原始的 body
+// 会解析 body 中的 code，err_code，message，并包装对应的错误分类
+func NewAPIError(status int, body []byte, trace string) error {
+	e := &Err{
+		code:   status,
+		text:   string(body),
+		trace:  trace,
+		status: status,
+	}
+	var b apiErrBody
+	if err := json.Unmarshal(body, &b); err == nil {
+		e.apiCode = b.Code
+		e.errCode = b.ErrCode
+		e.message = b.Message
+		if e.trace == "" {
+			e.trace = b.TraceID
+		}
+	}
+	e.err = apiCategory(status, e.errCode, e.apiCode)
+	return e
+}
+
+// apiCategory 获取错误的分类，优先使用平台错误码
+func apiCategory(status int, codes ...int) error {
+	for _, code := range codes {
+		if category := apiCodeToCategory(code); category != nil {
+			return category
+		}
+	}
+	switch {
+	case status == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case status == http.StatusForbidden:
+		return ErrNoPermission
+	case status == http.StatusNotFound:
+		return ErrNotFound
+	case status == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case status >= http.StatusInternalServerError:
+		return ErrServerError
+	}
+	return nil
+}
+
+func apiCodeToCategory(code int) error {
+	if code == 0 {
+		return nil
+	}
+	apiCodeCategoryLock.RLock()
+	defer apiCodeCategoryLock.RUnlock()
+	return apiCodeCategory[code]
+}
+
+// Status 获取 openapi 响应的 http 状态码
+func (e Err) Status() int {
+	return e.status
+}
+
+// APICode 获取 openapi 响应 body 中的 code
+func (e Err) APICode() int {
+	return e.apiCode
+}
+
+// ErrCode 获取 openapi 响应 body 中的 err_code
+func (e Err) ErrCode() int {
+	return e.errCode
+}
+
+// Message 获取 openapi 响应 body 中的 message
+func (e Err) Message() string {
+	return e.message
+}
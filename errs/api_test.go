@@ -0,0 +1,73 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAPIError(t *testing.T) {
+	body := []byte(`{"message":"push message is waiting for audit","code":304023,"err_code":304023,"trace_id":"t1"}`)
+	err := NewAPIError(http.StatusAccepted, body, "")
+	assert.True(t, errors.Is(err, ErrMessageAudit))
+	assert.False(t, errors.Is(err, ErrNoPermission))
+
+	var e *Err
+	assert.True(t, errors.As(fmt.Errorf("post message: %w", err), &e))
+	assert.Equal(t, http.StatusAccepted, e.Status())
+	assert.Equal(t, http.StatusAccepted, e.Code())
+	assert.Equal(t, 304023, e.ErrCode())
+	assert.Equal(t, 304023, e.APICode())
+	assert.Equal(t, "push message is waiting for audit", e.Message())
+	assert.Equal(t, "t1", e.Trace())
+	assert.Equal(t, string(body), e.Text())
+
+	tests := []struct {
+		status int
+		body   string
+		want   error
+	}{
+		{http.StatusForbidden, `{"code":1}`, ErrNoPermission},
+		{http.StatusNotFound, `not json`, ErrNotFound},
+		{http.StatusTooManyRequests, ``, ErrRateLimited},
+		{http.StatusBadGateway, ``, ErrServerError},
+		{http.StatusBadRequest, `{"code":22009}`, ErrRateLimited},
+		{http.StatusUnauthorized, `{"err_code":11244}`, ErrUnauthorized},
+		{http.StatusBadRequest, `{"code":40034024,"err_code":40034024}`, ErrReplyExpired},
+		{http.StatusBadRequest, `{"code":40034025,"err_code":40034025}`, ErrReplyExpired},
+	}
+	for _, tt := range tests {
+		assert.True(t, errors.Is(NewAPIError(tt.status, []byte(tt.body), ""), tt.want), tt.body)
+	}
+
+	restoreAPICode(t, 12345678)
+	RegisterAPICode(12345678, ErrNoPermission)
+	assert.True(t, errors.Is(NewAPIError(http.StatusBadRequest, []byte(`{"err_code":12345678}`), ""), ErrNoPermission))
+	assert.Nil(t, errors.Unwrap(NewAPIError(http.StatusBadRequest, []byte(`{"code":1}`), "")))
+}
+
+// restoreAPICode 测试结束之后恢复错误码的分类，避免影响其他测试
+func restoreAPICode(t *testing.T, code int) {
+	apiCodeCategoryLock.RLock()
+	old, ok := apiCodeCategory[code]
+	apiCodeCategoryLock.RUnlock()
+	t.Cleanup(func() {
+		apiCodeCategoryLock.Lock()
+		defer apiCodeCategoryLock.Unlock()
+		if ok {
+			apiCodeCategory[code] = old
+		} else {
+			delete(apiCodeCategory, code)
+		}
+	})
+}
+
+func TestWrap(t *testing.T) {
+	cause := errors.New("close 4914")
+	err := Wrap(CodeConnCloseCantIdentify, cause.Error(), cause)
+	assert.True(t, errors.Is(err, cause))
+	assert.Equal(t, CodeConnCloseCantIdentify, Error(fmt.Errorf("listening: %w", err)).Code())
+}
@@ -2,6 +2,7 @@
 package errs
 
 import (
+	"errors"
 	"fmt"
 )
 
@@ -14,6 +15,16 @@ var (
 	ErrURLInvalid = New(CodeConnCloseCantIdentify, "ws ap url is invalid")
 	// ErrSessionLimit session 数量受到限制
 	ErrSessionLimit = New(CodeConnCloseCantIdentify, "session num limit")
+	// ErrConnShutdown 连接被优雅关闭，不需要重连
+	ErrConnShutdown = New(CodeConnShutdown, "connection shutdown")
+	// ErrHeartbeatTimeout 心跳 ack 超时，连接可能已经僵死，需要重连
+	ErrHeartbeatTimeout = New(CodeHeartbeatTimeout, "heartbeat ack timeout")
+	// ErrRateLimited 本地限频，在 context 的 deadline 之前无法获取到令牌
+	ErrRateLimited = New(CodeRateLimited, "rate limited")
+	// ErrStreamClosed 流式消息已经结束，不能继续写入
+	ErrStreamClosed = New(CodeStreamClosed, "stream closed")
+	// ErrInvalidPayload 请求参数不符合平台的限制，在请求发出之前校验失败
+	ErrInvalidPayload = New(CodeInvalidPayload, "invalid payload")
 
 	// ErrNotFoundOpenAPI 未找到对应版本的openapi实现
 	ErrNotFoundOpenAPI = New(CodeNotFoundOpenAPI, "not found openapi version")
@@ -35,6 +46,16 @@ const (
 	CodeConnCloseCantIdentify = 9006
 	// CodePagerIsNil 分页器为空
 	CodePagerIsNil = 9007
+	// CodeConnShutdown 连接被优雅关闭，不需要重连
+	CodeConnShutdown = 9008
+	// CodeHeartbeatTimeout 心跳 ack 超时，允许 resume
+	CodeHeartbeatTimeout = 9009
+	// CodeRateLimited 本地限频，请求没有发出
+	CodeRateLimited = 9010
+	// CodeStreamClosed 流式消息已经结束
+	CodeStreamClosed = 9011
+	// CodeInvalidPayload 请求参数校验失败，请求没有发出
+	CodeInvalidPayload = 9012
 )
 
 // websocket错误码
@@ -68,6 +89,13 @@ type Err struct {
 	code  int
 	text  string
 	trace string // 错误追踪ID，可用于向平台反馈问题
+	err   error  // 被包装的错误，openapi 的错误为错误的分类，如 ErrNoPermission
+
+	// openapi 调用失败时，从响应中解析出的信息
+	status  int    // http 状态码
+	apiCode int    // 响应 body 中的 code
+	errCode int    // 响应 body 中的 err_code
+	message string // 响应 body 中的 message
 }
 
 // New 创建一个新错误
@@ -82,9 +110,22 @@ func New(code int, text string, trace ...string) error {
 	return err
 }
 
+// Wrap 创建一个包装了 err 的新错误，可以通过 errors.Is 与 errors.As 判断被包装的错误
+func Wrap(code int, text string, err error, trace ...string) error {
+	e := New(code, text, trace...).(*Err)
+	e.err = err
+	return e
+}
+
+// Invalid 创建请求参数校验失败的错误，field 为出错的字段，可以通过 errors.Is(err, ErrInvalidPayload) 判断
+func Invalid(field, format string, args ...interface{}) error {
+	return Wrap(CodeInvalidPayload, field+": "+fmt.Sprintf(format, args...), ErrInvalidPayload)
+}
+
 // Error 将错误转换为 sdk 的错误类型
 func Error(err error) *Err {
-	if e, ok := err.(*Err); ok {
+	var e *Err
+	if errors.As(err, &e) {
 		return e
 	}
 	return &Err{
@@ -112,3 +153,8 @@ func (e Err) Text() string {
 func (e Err) Trace() string {
 	return e.trace
 }
+
+// Unwrap 获取被包装的错误
+func (e Err) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,45 @@
+package event
+
+import (
+	"context"
+
+	"github.com/tencent-connect/botgo/dto"
+)
+
+type contextKey int
+
+const (
+	sessionContextKey contextKey = iota
+	shardContextKey
+	eventIDContextKey
+)
+
+// NewContext 基于事件 payload 生成 context，附加 session，shard 与事件 ID
+func NewContext(ctx context.Context, payload *dto.WSPayload) context.Context {
+	if payload.Session != nil {
+		ctx = context.WithValue(ctx, sessionContextKey, payload.Session)
+		ctx = context.WithValue(ctx, shardContextKey, payload.Session.Shards)
+	}
+	if payload.EventID != "" {
+		ctx = context.WithValue(ctx, eventIDContextKey, payload.EventID)
+	}
+	return ctx
+}
+
+// SessionFromContext 从 context 中获取事件所属连接的 session
+func SessionFromContext(ctx context.Context) (*dto.Session, bool) {
+	s, ok := ctx.Value(sessionContextKey).(*dto.Session)
+	return s, ok
+}
+
+// ShardFromContext 从 context 中获取事件所属连接的 shard 信息
+func ShardFromContext(ctx context.Context) (dto.ShardConfig, bool) {
+	s, ok := ctx.Value(shardContextKey).(dto.ShardConfig)
+	return s, ok
+}
+
+// EventIDFromContext 从 context 中获取事件 ID
+func EventIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(eventIDContextKey).(string)
+	return id
+}
@@ -0,0 +1,95 @@
+package event
+
+import (
+	"context"
+	"sync"
+
+	"github.com/tencent-connect/botgo/dto"
+)
+
+// DefaultDispatcher 默认的事件分发器，包级别的 RegisterHandlers，ParseAndHandle 等方法都作用于它
+var DefaultDispatcher = newDispatcher(&DefaultHandlers)
+
+// Dispatcher 事件分发器，持有独立的 handler 集合与事件解析映射
+// 同一进程中运行多个机器人时，可以为每个机器人创建各自的分发器，互不影响
+type Dispatcher struct {
+	handlers *Handlers
+
+	parseFuncMapLock sync.RWMutex
+	parseFuncMap     map[dto.OPCode]map[dto.EventType]eventParseCtxFunc
+
+	middlewareLock sync.RWMutex
+	middlewares    []Middleware
+	chain          Handler // 包装了中间件的处理链，注册中间件时重新生成
+}
+
+// NewDispatcher 创建一个新的事件分发器
+func NewDispatcher() *Dispatcher {
+	return newDispatcher(&Handlers{})
+}
+
+func newDispatcher(handlers *Handlers) *Dispatcher {
+	d := &Dispatcher{
+		handlers: handlers,
+	}
+	d.parseFuncMap = d.newParseFuncMap()
+	return d
+}
+
+// Handlers 获取分发器持有的 handler 集合
+func (d *Dispatcher) Handlers() *Handlers {
+	return d.handlers
+}
+
+// RegisterHandler 注册回调事件处理器，会覆盖 sdk 内置的同类型事件解析
+func (d *Dispatcher) RegisterHandler(opCode dto.OPCode, eventType dto.EventType, handler eventParseFunc) {
+	d.RegisterCtxHandler(opCode, eventType,
+		func(_ context.Context, event *dto.WSPayload, message []byte) error {
+			return handler(event, message)
+		},
+	)
+}
+
+// RegisterCtxHandler 注册携带 context 的回调事件处理器，会覆盖 sdk 内置的同类型事件解析
+func (d *Dispatcher) RegisterCtxHandler(opCode dto.OPCode, eventType dto.EventType, handler eventParseCtxFunc) {
+	d.parseFuncMapLock.Lock()
+	defer d.parseFuncMapLock.Unlock()
+	if d.parseFuncMap[opCode] == nil {
+		d.parseFuncMap[opCode] = make(map[dto.EventType]eventParseCtxFunc)
+	}
+	d.parseFuncMap[opCode][eventType] = handler
+}
+
+func (d *Dispatcher) getHandler(opCode dto.OPCode, eventType dto.EventType) (eventParseCtxFunc, bool) {
+	d.parseFuncMapLock.RLock()
+	defer d.parseFuncMapLock.RUnlock()
+	f, ok := d.parseFuncMap[opCode][eventType]
+	return f, ok
+}
+
+// ParseAndHandle 处理回调事件
+func (d *Dispatcher) ParseAndHandle(payload *dto.WSPayload) error {
+	return d.ParseAndHandleContext(context.Background(), payload)
+}
+
+// ParseAndHandleContext 处理回调事件，ctx 由连接或者 http 请求的生命周期控制，
+// 会在其上附加 session，shard 与事件 ID 之后传递给携带 context 的 handler
+func (d *Dispatcher) ParseAndHandleContext(ctx context.Context, payload *dto.WSPayload) error {
+	return d.getChain()(NewContext(ctx, payload), payload)
+}
+
+// dispatch 按照事件类型解析并投递给注册的 handler，是中间件链的最内层
+func (d *Dispatcher) dispatch(ctx context.Context, payload *dto.WSPayload) error {
+	// 指定类型的 handler
+	if h, ok := d.getHandler(payload.OPCode, payload.Type); ok {
+		return h(ctx, payload, payload.RawMessage)
+	}
+	// 透传handler，如果未注册具体类型的 handler，会统一投递到这个 handler
+	if d.handlers.PlainCtx != nil {
+		return d.handlers.PlainCtx(ctx, payload, payload.RawMessage)
+	}
+	if d.handlers.Plain != nil {
+		return d.handlers.Plain(payload, payload.RawMessage)
+	}
+	return nil
+}
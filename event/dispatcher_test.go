@@ -0,0 +1,65 @@
+package event
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tencent-connect/botgo/dto"
+)
+
+func TestDispatcher_ParseAndHandle(t *testing.T) {
+	var got []string
+	d1 := NewDispatcher()
+	d2 := NewDispatcher()
+	i := d1.RegisterHandlers(ATMessageEventHandler(func(event *dto.WSPayload, data *dto.WSATMessageData) error {
+		got = append(got, "d1:"+data.Content)
+		return nil
+	}))
+	d2.RegisterHandlers(ATMessageEventHandler(func(event *dto.WSPayload, data *dto.WSATMessageData) error {
+		got = append(got, "d2:"+data.Content)
+		return nil
+	}))
+	assert.Equal(t, dto.IntentGuildAtMessage, i&dto.IntentGuildAtMessage)
+
+	payload := &dto.WSPayload{
+		WSPayloadBase: dto.WSPayloadBase{OPCode: dto.WSDispatchEvent, Type: dto.EventAtMessageCreate},
+		RawMessage:    []byte(`{"op":0,"t":"AT_MESSAGE_CREATE","d":{"content":"hi"}}`),
+	}
+	assert.Nil(t, d1.ParseAndHandle(payload))
+	assert.Nil(t, d2.ParseAndHandle(payload))
+	assert.Equal(t, []string{"d1:hi", "d2:hi"}, got)
+	assert.Nil(t, DefaultHandlers.ATMessage)
+}
+
+func TestDispatcher_ParseAndHandleContext(t *testing.T) {
+	d := NewDispatcher()
+	var plainCalled bool
+	d.RegisterHandlers(
+		C2CMessageEventHandler(func(event *dto.WSPayload, data *dto.WSC2CMessageData) error {
+			plainCalled = true
+			return nil
+		}),
+		C2CMessageEventCtxHandler(func(ctx context.Context, event *dto.WSPayload, data *dto.WSC2CMessageData) error {
+			session, ok := SessionFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, "app", session.AppID)
+			shard, _ := ShardFromContext(ctx)
+			assert.Equal(t, uint32(1), shard.ShardID)
+			assert.Equal(t, "event-id", EventIDFromContext(ctx))
+			return ctx.Err()
+		}),
+	)
+	payload := &dto.WSPayload{
+		WSPayloadBase: dto.WSPayloadBase{
+			OPCode: dto.WSDispatchEvent, Type: dto.EventC2CMessageCreate, EventID: "event-id",
+		},
+		RawMessage: []byte(`{"op":0,"t":"C2C_MESSAGE_CREATE","d":{"content":"hi"}}`),
+		Session:    &dto.Session{AppID: "app", Shards: dto.ShardConfig{ShardID: 1, ShardCount: 2}},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	assert.Nil(t, d.ParseAndHandleContext(ctx, payload))
+	cancel()
+	assert.Equal(t, context.Canceled, d.ParseAndHandleContext(ctx, payload))
+	assert.False(t, plainCalled)
+}
@@ -2,98 +2,92 @@
 package event
 
 import (
+	"context"
 	"encoding/json"
-	"sync"
 
 	"github.com/tidwall/gjson" // 由于回包的 d 类型不确定，gjson 用于从回包json中提取 d 并进行针对性的解析
 
 	"github.com/tencent-connect/botgo/dto"
 )
 
-var eventParseFuncMapLock = new(sync.RWMutex)
-var eventParseFuncMap = map[dto.OPCode]map[dto.EventType]eventParseFunc{
-	dto.WSDispatchEvent: {
-		dto.EventGuildCreate: guildHandler,
-		dto.EventGuildUpdate: guildHandler,
-		dto.EventGuildDelete: guildHandler,
-
-		dto.EventChannelCreate: channelHandler,
-		dto.EventChannelUpdate: channelHandler,
-		dto.EventChannelDelete: channelHandler,
-
-		dto.EventGuildMemberAdd:    guildMemberHandler,
-		dto.EventGuildMemberUpdate: guildMemberHandler,
-		dto.EventGuildMemberRemove: guildMemberHandler,
-
-		dto.EventMessageCreate: messageHandler,
-		dto.EventMessageDelete: messageDeleteHandler,
-
-		dto.EventMessageReactionAdd:    messageReactionHandler,
-		dto.EventMessageReactionRemove: messageReactionHandler,
-
-		dto.EventAtMessageCreate:     atMessageHandler,
-		dto.EventPublicMessageDelete: publicMessageDeleteHandler,
-
-		dto.EventDirectMessageCreate: directMessageHandler,
-		dto.EventDirectMessageDelete: directMessageDeleteHandler,
-
-		dto.EventAudioStart:  audioHandler,
-		dto.EventAudioFinish: audioHandler,
-		dto.EventAudioOnMic:  audioHandler,
-		dto.EventAudioOffMic: audioHandler,
-
-		dto.EventMessageAuditPass:   messageAuditHandler,
-		dto.EventMessageAuditReject: messageAuditHandler,
-
-		dto.EventForumThreadCreate: threadHandler,
-		dto.EventForumThreadUpdate: threadHandler,
-		dto.EventForumThreadDelete: threadHandler,
-		dto.EventForumPostCreate:   postHandler,
-		dto.EventForumPostDelete:   postHandler,
-		dto.EventForumReplyCreate:  replyHandler,
-		dto.EventForumReplyDelete:  replyHandler,
-		dto.EventForumAuditResult:  forumAuditHandler,
-
-		dto.EventInteractionCreate:    interactionHandler,
-		dto.EventGroupAtMessageCreate: groupAtMessageHandler,
-		dto.EventC2CMessageCreate:     c2cMessageHandler,
-		dto.EventSubscribeMsgStatus:   subscribeStatusHandler,
-		dto.EventC2CFriendAdd:         c2cFriendAddHandler,
-		dto.EventC2CFriendDel:         c2cFriendDelHandler,
-		dto.EventEnterAIO:             enterAIOHandler,
-	},
+// newParseFuncMap 生成绑定到分发器的事件解析映射，每个分发器持有独立的一份
+func (d *Dispatcher) newParseFuncMap() map[dto.OPCode]map[dto.EventType]eventParseCtxFunc {
+	return map[dto.OPCode]map[dto.EventType]eventParseCtxFunc{
+		dto.WSDispatchEvent: {
+			dto.EventGuildCreate: d.guildHandler,
+			dto.EventGuildUpdate: d.guildHandler,
+			dto.EventGuildDelete: d.guildHandler,
+
+			dto.EventChannelCreate: d.channelHandler,
+			dto.EventChannelUpdate: d.channelHandler,
+			dto.EventChannelDelete: d.channelHandler,
+
+			dto.EventGuildMemberAdd:    d.guildMemberHandler,
+			dto.EventGuildMemberUpdate: d.guildMemberHandler,
+			dto.EventGuildMemberRemove: d.guildMemberHandler,
+
+			dto.EventMessageCreate: d.messageHandler,
+			dto.EventMessageDelete: d.messageDeleteHandler,
+
+			dto.EventMessageReactionAdd:    d.messageReactionHandler,
+			dto.EventMessageReactionRemove: d.messageReactionHandler,
+
+			dto.EventAtMessageCreate:     d.atMessageHandler,
+			dto.EventPublicMessageDelete: d.publicMessageDeleteHandler,
+
+			dto.EventDirectMessageCreate: d.directMessageHandler,
+			dto.EventDirectMessageDelete: d.directMessageDeleteHandler,
+
+			dto.EventAudioStart:  d.audioHandler,
+			dto.EventAudioFinish: d.audioHandler,
+			dto.EventAudioOnMic:  d.audioHandler,
+			dto.EventAudioOffMic: d.audioHandler,
+
+			dto.EventMessageAuditPass:   d.messageAuditHandler,
+			dto.EventMessageAuditReject: d.messageAuditHandler,
+
+			dto.EventForumThreadCreate: d.threadHandler,
+			dto.EventForumThreadUpdate: d.threadHandler,
+			dto.EventForumThreadDelete: d.threadHandler,
+			dto.EventForumPostCreate:   d.postHandler,
+			dto.EventForumPostDelete:   d.postHandler,
+			dto.EventForumReplyCreate:  d.replyHandler,
+			dto.EventForumReplyDelete:  d.replyHandler,
+			dto.EventForumAuditResult:  d.forumAuditHandler,
+
+			dto.EventInteractionCreate:    d.interactionHandler,
+			dto.EventGroupAtMessageCreate: d.groupAtMessageHandler,
+			dto.EventC2CMessageCreate:     d.c2cMessageHandler,
+			dto.EventSubscribeMsgStatus:   d.subscribeStatusHandler,
+			dto.EventC2CFriendAdd:         d.c2cFriendAddHandler,
+			dto.EventC2CFriendDel:         d.c2cFriendDelHandler,
+			dto.EventEnterAIO:             d.enterAIOHandler,
+		},
+	}
 }
 
-// RegisterHandler 注册回调事件处理器
+type eventParseFunc func(event *dto.WSPayload, message []byte) error
+
+type eventParseCtxFunc func(ctx context.Context, event *dto.WSPayload, message []byte) error
+
+// RegisterHandler 注册回调事件处理器，注册到默认分发器
 func RegisterHandler(opCode dto.OPCode, eventType dto.EventType, handler eventParseFunc) {
-	eventParseFuncMapLock.Lock()
-	defer eventParseFuncMapLock.Unlock()
-	if eventParseFuncMap[opCode] == nil {
-		eventParseFuncMap[opCode] = make(map[dto.EventType]eventParseFunc)
-	}
-	eventParseFuncMap[opCode][eventType] = handler
+	DefaultDispatcher.RegisterHandler(opCode, eventType, handler)
 }
 
-func getHandler(opCode dto.OPCode, eventType dto.EventType) (eventParseFunc, bool) {
-	eventParseFuncMapLock.RLock()
-	defer eventParseFuncMapLock.RUnlock()
-	f, ok := eventParseFuncMap[opCode][eventType]
-	return f, ok
+// RegisterCtxHandler 注册携带 context 的回调事件处理器，注册到默认分发器
+func RegisterCtxHandler(opCode dto.OPCode, eventType dto.EventType, handler eventParseCtxFunc) {
+	DefaultDispatcher.RegisterCtxHandler(opCode, eventType, handler)
 }
 
-type eventParseFunc func(event *dto.WSPayload, message []byte) error
-
-// ParseAndHandle 处理回调事件
+// ParseAndHandle 处理回调事件，使用默认分发器
 func ParseAndHandle(payload *dto.WSPayload) error {
-	// 指定类型的 handler
-	if h, ok := getHandler(payload.OPCode, payload.Type); ok {
-		return h(payload, payload.RawMessage)
-	}
-	// 透传handler，如果未注册具体类型的 handler，会统一投递到这个 handler
-	if DefaultHandlers.Plain != nil {
-		return DefaultHandlers.Plain(payload, payload.RawMessage)
-	}
-	return nil
+	return DefaultDispatcher.ParseAndHandle(payload)
+}
+
+// ParseAndHandleContext 处理回调事件，使用默认分发器，ctx 会传递给携带 context 的 handler
+func ParseAndHandleContext(ctx context.Context, payload *dto.WSPayload) error {
+	return DefaultDispatcher.ParseAndHandleContext(ctx, payload)
 }
 
 // ParseData 解析数据
@@ -102,255 +96,324 @@ func ParseData(message []byte, target interface{}) error {
 	return json.Unmarshal([]byte(data.String()), target)
 }
 
-func guildHandler(payload *dto.WSPayload, message []byte) error {
+func (d *Dispatcher) guildHandler(ctx context.Context, payload *dto.WSPayload, message []byte) error {
 	data := &dto.WSGuildData{}
 	if err := ParseData(message, data); err != nil {
 		return err
 	}
-	if DefaultHandlers.Guild != nil {
-		return DefaultHandlers.Guild(payload, data)
+	if d.handlers.GuildCtx != nil {
+		return d.handlers.GuildCtx(ctx, payload, data)
+	}
+	if d.handlers.Guild != nil {
+		return d.handlers.Guild(payload, data)
 	}
 	return nil
 }
 
-func channelHandler(payload *dto.WSPayload, message []byte) error {
+func (d *Dispatcher) channelHandler(ctx context.Context, payload *dto.WSPayload, message []byte) error {
 	data := &dto.WSChannelData{}
 	if err := ParseData(message, data); err != nil {
 		return err
 	}
-	if DefaultHandlers.Channel != nil {
-		return DefaultHandlers.Channel(payload, data)
+	if d.handlers.ChannelCtx != nil {
+		return d.handlers.ChannelCtx(ctx, payload, data)
+	}
+	if d.handlers.Channel != nil {
+		return d.handlers.Channel(payload, data)
 	}
 	return nil
 }
 
-func guildMemberHandler(payload *dto.WSPayload, message []byte) error {
+func (d *Dispatcher) guildMemberHandler(ctx context.Context, payload *dto.WSPayload, message []byte) error {
 	data := &dto.WSGuildMemberData{}
 	if err := ParseData(message, data); err != nil {
 		return err
 	}
-	if DefaultHandlers.GuildMember != nil {
-		return DefaultHandlers.GuildMember(payload, data)
+	if d.handlers.GuildMemberCtx != nil {
+		return d.handlers.GuildMemberCtx(ctx, payload, data)
+	}
+	if d.handlers.GuildMember != nil {
+		return d.handlers.GuildMember(payload, data)
 	}
 	return nil
 }
 
-func messageHandler(payload *dto.WSPayload, message []byte) error {
+func (d *Dispatcher) messageHandler(ctx context.Context, payload *dto.WSPayload, message []byte) error {
 	data := &dto.WSMessageData{}
 	if err := ParseData(message, data); err != nil {
 		return err
 	}
-	if DefaultHandlers.Message != nil {
-		return DefaultHandlers.Message(payload, data)
+	if d.handlers.MessageCtx != nil {
+		return d.handlers.MessageCtx(ctx, payload, data)
+	}
+	if d.handlers.Message != nil {
+		return d.handlers.Message(payload, data)
 	}
 	return nil
 }
 
-func messageDeleteHandler(payload *dto.WSPayload, message []byte) error {
+func (d *Dispatcher) messageDeleteHandler(ctx context.Context, payload *dto.WSPayload, message []byte) error {
 	data := &dto.WSMessageDeleteData{}
 	if err := ParseData(message, data); err != nil {
 		return err
 	}
-	if DefaultHandlers.MessageDelete != nil {
-		return DefaultHandlers.MessageDelete(payload, data)
+	if d.handlers.MessageDeleteCtx != nil {
+		return d.handlers.MessageDeleteCtx(ctx, payload, data)
+	}
+	if d.handlers.MessageDelete != nil {
+		return d.handlers.MessageDelete(payload, data)
 	}
 	return nil
 }
 
-func messageReactionHandler(payload *dto.WSPayload, message []byte) error {
+func (d *Dispatcher) messageReactionHandler(ctx context.Context, payload *dto.WSPayload, message []byte) error {
 	data := &dto.WSMessageReactionData{}
 	if err := ParseData(message, data); err != nil {
 		return err
 	}
-	if DefaultHandlers.MessageReaction != nil {
-		return DefaultHandlers.MessageReaction(payload, data)
+	if d.handlers.MessageReactionCtx != nil {
+		return d.handlers.MessageReactionCtx(ctx, payload, data)
+	}
+	if d.handlers.MessageReaction != nil {
+		return d.handlers.MessageReaction(payload, data)
 	}
 	return nil
 }
 
-func atMessageHandler(payload *dto.WSPayload, message []byte) error {
+func (d *Dispatcher) atMessageHandler(ctx context.Context, payload *dto.WSPayload, message []byte) error {
 	data := &dto.WSATMessageData{}
 	if err := ParseData(message, data); err != nil {
 		return err
 	}
-	if DefaultHandlers.ATMessage != nil {
-		return DefaultHandlers.ATMessage(payload, data)
+	if d.handlers.ATMessageCtx != nil {
+		return d.handlers.ATMessageCtx(ctx, payload, data)
+	}
+	if d.handlers.ATMessage != nil {
+		return d.handlers.ATMessage(payload, data)
 	}
 	return nil
 }
 
-func groupAtMessageHandler(payload *dto.WSPayload, message []byte) error {
+func (d *Dispatcher) groupAtMessageHandler(ctx context.Context, payload *dto.WSPayload, message []byte) error {
 	data := &dto.WSGroupATMessageData{}
 	if err := ParseData(message, data); err != nil {
 		return err
 	}
-	if DefaultHandlers.GroupATMessage != nil {
-		return DefaultHandlers.GroupATMessage(payload, data)
+	if d.handlers.GroupATMessageCtx != nil {
+		return d.handlers.GroupATMessageCtx(ctx, payload, data)
+	}
+	if d.handlers.GroupATMessage != nil {
+		return d.handlers.GroupATMessage(payload, data)
 	}
 	return nil
 }
 
-func c2cMessageHandler(payload *dto.WSPayload, message []byte) error {
+func (d *Dispatcher) c2cMessageHandler(ctx context.Context, payload *dto.WSPayload, message []byte) error {
 	data := &dto.WSC2CMessageData{}
 	if err := ParseData(message, data); err != nil {
 		return err
 	}
-	if DefaultHandlers.C2CMessage != nil {
-		return DefaultHandlers.C2CMessage(payload, data)
+	if d.handlers.C2CMessageCtx != nil {
+		return d.handlers.C2CMessageCtx(ctx, payload, data)
+	}
+	if d.handlers.C2CMessage != nil {
+		return d.handlers.C2CMessage(payload, data)
 	}
 	return nil
 }
 
-func subscribeStatusHandler(payload *dto.WSPayload, message []byte) error {
+func (d *Dispatcher) subscribeStatusHandler(ctx context.Context, payload *dto.WSPayload, message []byte) error {
 	data := &dto.WSSubscribeMsgStatus{}
 	if err := ParseData(message, data); err != nil {
 		return err
 	}
-	if DefaultHandlers.SubscribeMsgStatus != nil {
-		return DefaultHandlers.SubscribeMsgStatus(payload, data)
+	if d.handlers.SubscribeMsgStatusCtx != nil {
+		return d.handlers.SubscribeMsgStatusCtx(ctx, payload, data)
+	}
+	if d.handlers.SubscribeMsgStatus != nil {
+		return d.handlers.SubscribeMsgStatus(payload, data)
 	}
 	return nil
 }
 
-func c2cFriendDelHandler(payload *dto.WSPayload, message []byte) error {
+func (d *Dispatcher) c2cFriendDelHandler(ctx context.Context, payload *dto.WSPayload, message []byte) error {
 	data := &dto.WSC2CFriendData{}
 	if err := ParseData(message, data); err != nil {
 		return err
 	}
-	if DefaultHandlers.C2CFriend != nil {
-		return DefaultHandlers.C2CFriend(payload, data)
+	if d.handlers.C2CFriendCtx != nil {
+		return d.handlers.C2CFriendCtx(ctx, payload, data)
+	}
+	if d.handlers.C2CFriend != nil {
+		return d.handlers.C2CFriend(payload, data)
 	}
 	return nil
 }
 
-func c2cFriendAddHandler(payload *dto.WSPayload, message []byte) error {
+func (d *Dispatcher) c2cFriendAddHandler(ctx context.Context, payload *dto.WSPayload, message []byte) error {
 	data := &dto.WSC2CFriendData{}
 	if err := ParseData(message, data); err != nil {
 		return err
 	}
-	if DefaultHandlers.C2CFriend != nil {
-		return DefaultHandlers.C2CFriend(payload, data)
+	if d.handlers.C2CFriendCtx != nil {
+		return d.handlers.C2CFriendCtx(ctx, payload, data)
+	}
+	if d.handlers.C2CFriend != nil {
+		return d.handlers.C2CFriend(payload, data)
 	}
 	return nil
 }
 
-func publicMessageDeleteHandler(payload *dto.WSPayload, message []byte) error {
+func (d *Dispatcher) publicMessageDeleteHandler(ctx context.Context, payload *dto.WSPayload, message []byte) error {
 	data := &dto.WSPublicMessageDeleteData{}
 	if err := ParseData(message, data); err != nil {
 		return err
 	}
-	if DefaultHandlers.PublicMessageDelete != nil {
-		return DefaultHandlers.PublicMessageDelete(payload, data)
+	if d.handlers.PublicMessageDeleteCtx != nil {
+		return d.handlers.PublicMessageDeleteCtx(ctx, payload, data)
+	}
+	if d.handlers.PublicMessageDelete != nil {
+		return d.handlers.PublicMessageDelete(payload, data)
 	}
 	return nil
 }
 
-func directMessageHandler(payload *dto.WSPayload, message []byte) error {
+func (d *Dispatcher) directMessageHandler(ctx context.Context, payload *dto.WSPayload, message []byte) error {
 	data := &dto.WSDirectMessageData{}
 	if err := ParseData(message, data); err != nil {
 		return err
 	}
-	if DefaultHandlers.DirectMessage != nil {
-		return DefaultHandlers.DirectMessage(payload, data)
+	if d.handlers.DirectMessageCtx != nil {
+		return d.handlers.DirectMessageCtx(ctx, payload, data)
+	}
+	if d.handlers.DirectMessage != nil {
+		return d.handlers.DirectMessage(payload, data)
 	}
 	return nil
 }
 
-func directMessageDeleteHandler(payload *dto.WSPayload, message []byte) error {
+func (d *Dispatcher) directMessageDeleteHandler(ctx context.Context, payload *dto.WSPayload, message []byte) error {
 	data := &dto.WSDirectMessageDeleteData{}
 	if err := ParseData(message, data); err != nil {
 		return err
 	}
-	if DefaultHandlers.DirectMessageDelete != nil {
-		return DefaultHandlers.DirectMessageDelete(payload, data)
+	if d.handlers.DirectMessageDeleteCtx != nil {
+		return d.handlers.DirectMessageDeleteCtx(ctx, payload, data)
+	}
+	if d.handlers.DirectMessageDelete != nil {
+		return d.handlers.DirectMessageDelete(payload, data)
 	}
 	return nil
 }
 
-func audioHandler(payload *dto.WSPayload, message []byte) error {
+func (d *Dispatcher) audioHandler(ctx context.Context, payload *dto.WSPayload, message []byte) error {
 	data := &dto.WSAudioData{}
 	if err := ParseData(message, data); err != nil {
 		return err
 	}
-	if DefaultHandlers.Audio != nil {
-		return DefaultHandlers.Audio(payload, data)
+	if d.handlers.AudioCtx != nil {
+		return d.handlers.AudioCtx(ctx, payload, data)
+	}
+	if d.handlers.Audio != nil {
+		return d.handlers.Audio(payload, data)
 	}
 	return nil
 }
 
-func threadHandler(payload *dto.WSPayload, message []byte) error {
+func (d *Dispatcher) threadHandler(ctx context.Context, payload *dto.WSPayload, message []byte) error {
 	data := &dto.WSThreadData{}
 	if err := ParseData(message, data); err != nil {
 		return err
 	}
-	if DefaultHandlers.Thread != nil {
-		return DefaultHandlers.Thread(payload, data)
+	if d.handlers.ThreadCtx != nil {
+		return d.handlers.ThreadCtx(ctx, payload, data)
+	}
+	if d.handlers.Thread != nil {
+		return d.handlers.Thread(payload, data)
 	}
 	return nil
 }
 
-func postHandler(payload *dto.WSPayload, message []byte) error {
+func (d *Dispatcher) postHandler(ctx context.Context, payload *dto.WSPayload, message []byte) error {
 	data := &dto.WSPostData{}
 	if err := ParseData(message, data); err != nil {
 		return err
 	}
-	if DefaultHandlers.Post != nil {
-		return DefaultHandlers.Post(payload, data)
+	if d.handlers.PostCtx != nil {
+		return d.handlers.PostCtx(ctx, payload, data)
+	}
+	if d.handlers.Post != nil {
+		return d.handlers.Post(payload, data)
 	}
 	return nil
 }
 
-func replyHandler(payload *dto.WSPayload, message []byte) error {
+func (d *Dispatcher) replyHandler(ctx context.Context, payload *dto.WSPayload, message []byte) error {
 	data := &dto.WSReplyData{}
 	if err := ParseData(message, data); err != nil {
 		return err
 	}
-	if DefaultHandlers.Reply != nil {
-		return DefaultHandlers.Reply(payload, data)
+	if d.handlers.ReplyCtx != nil {
+		return d.handlers.ReplyCtx(ctx, payload, data)
+	}
+	if d.handlers.Reply != nil {
+		return d.handlers.Reply(payload, data)
 	}
 	return nil
 }
 
-func forumAuditHandler(payload *dto.WSPayload, message []byte) error {
+func (d *Dispatcher) forumAuditHandler(ctx context.Context, payload *dto.WSPayload, message []byte) error {
 	data := &dto.WSForumAuditData{}
 	if err := ParseData(message, data); err != nil {
 		return err
 	}
-	if DefaultHandlers.ForumAudit != nil {
-		return DefaultHandlers.ForumAudit(payload, data)
+	if d.handlers.ForumAuditCtx != nil {
+		return d.handlers.ForumAuditCtx(ctx, payload, data)
+	}
+	if d.handlers.ForumAudit != nil {
+		return d.handlers.ForumAudit(payload, data)
 	}
 	return nil
 }
 
-func messageAuditHandler(payload *dto.WSPayload, message []byte) error {
+func (d *Dispatcher) messageAuditHandler(ctx context.Context, payload *dto.WSPayload, message []byte) error {
 	data := &dto.WSMessageAuditData{}
 	if err := ParseData(message, data); err != nil {
 		return err
 	}
-	if DefaultHandlers.MessageAudit != nil {
-		return DefaultHandlers.MessageAudit(payload, data)
+	if d.handlers.MessageAuditCtx != nil {
+		return d.handlers.MessageAuditCtx(ctx, payload, data)
+	}
+	if d.handlers.MessageAudit != nil {
+		return d.handlers.MessageAudit(payload, data)
 	}
 	return nil
 }
 
-func interactionHandler(payload *dto.WSPayload, message []byte) error {
+func (d *Dispatcher) interactionHandler(ctx context.Context, payload *dto.WSPayload, message []byte) error {
 	data := &dto.WSInteractionData{}
 	if err := ParseData(message, data); err != nil {
 		return err
 	}
-	if DefaultHandlers.Interaction != nil {
-		return DefaultHandlers.Interaction(payload, data)
+	if d.handlers.InteractionCtx != nil {
+		return d.handlers.InteractionCtx(ctx, payload, data)
+	}
+	if d.handlers.Interaction != nil {
+		return d.handlers.Interaction(payload, data)
 	}
 	return nil
 }
 
-func enterAIOHandler(payload *dto.WSPayload, message []byte) error {
+func (d *Dispatcher) enterAIOHandler(ctx context.Context, payload *dto.WSPayload, message []byte) error {
 	data := &dto.WSEnterAIOData{}
 	if err := ParseData(message, data); err != nil {
 		return err
 	}
-	if DefaultHandlers.EnterAIO != nil {
-		return DefaultHandlers.EnterAIO(payload, data)
+	if d.handlers.EnterAIOCtx != nil {
+		return d.handlers.EnterAIOCtx(ctx, payload, data)
+	}
+	if d.handlers.EnterAIO != nil {
+		return d.handlers.EnterAIO(payload, data)
 	}
 	return nil
 }
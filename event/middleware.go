@@ -0,0 +1,65 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+
+	"github.com/tencent-connect/botgo/dto"
+	"github.com/tencent-connect/botgo/log"
+)
+
+// Handler 事件处理函数，中间件包装的对象，payload 为已经解析好基础字段（type，seq，事件 ID）的事件
+type Handler func(ctx context.Context, payload *dto.WSPayload) error
+
+// Middleware 事件中间件，用于在事件分发前后执行统一的逻辑，比如 panic 恢复，监控上报，限频，去重，日志等
+// 中间件可以通过 next 的返回值获取到业务 handler 返回的错误
+type Middleware func(next Handler) Handler
+
+// Use 为默认分发器注册中间件
+func Use(middlewares ...Middleware) {
+	DefaultDispatcher.Use(middlewares...)
+}
+
+// Use 注册中间件，先注册的中间件位于外层，先于后注册的中间件执行
+func (d *Dispatcher) Use(middlewares ...Middleware) {
+	d.middlewareLock.Lock()
+	defer d.middlewareLock.Unlock()
+	d.middlewares = append(d.middlewares, middlewares...)
+	h := Handler(d.dispatch)
+	for i := len(d.middlewares) - 1; i >= 0; i-- {
+		h = d.middlewares[i](h)
+	}
+	d.chain = h
+}
+
+// getChain 获取包装了中间件的处理链，没有注册中间件时直接返回事件分发方法
+func (d *Dispatcher) getChain() Handler {
+	d.middlewareLock.RLock()
+	defer d.middlewareLock.RUnlock()
+	if d.chain == nil {
+		return d.dispatch
+	}
+	return d.chain
+}
+
+// PanicBufLen Panic 堆栈大小
+var PanicBufLen = 1024
+
+// Recover panic 恢复中间件，handler 发生 panic 时打印堆栈并转换为错误返回，避免连接因为业务 panic 而断开
+func Recover() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, payload *dto.WSPayload) (err error) {
+			defer func() {
+				if e := recover(); e != nil {
+					buf := make([]byte, PanicBufLen)
+					buf = buf[:runtime.Stack(buf, false)]
+					log.Errorf("[PANIC]%s event:%s, id:%s\n%v\n%s\n",
+						payload.Session, payload.Type, payload.EventID, e, buf)
+					err = fmt.Errorf("panic: %v", e)
+				}
+			}()
+			return next(ctx, payload)
+		}
+	}
+}
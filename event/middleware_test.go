@@ -0,0 +1,54 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tencent-connect/botgo/dto"
+)
+
+func TestDispatcher_Use(t *testing.T) {
+	d := NewDispatcher()
+	handlerErr := errors.New("handler failed")
+	d.RegisterHandlers(GroupATMessageEventHandler(func(event *dto.WSPayload, data *dto.WSGroupATMessageData) error {
+		if data.Content == "panic" {
+			panic("boom")
+		}
+		return handlerErr
+	}))
+	var trace []string
+	var gotErr error
+	d.Use(
+		func(next Handler) Handler {
+			return func(ctx context.Context, payload *dto.WSPayload) error {
+				trace = append(trace, "outer:"+payload.EventID)
+				gotErr = next(ctx, payload)
+				return gotErr
+			}
+		},
+		Recover(),
+		func(next Handler) Handler {
+			return func(ctx context.Context, payload *dto.WSPayload) error {
+				trace = append(trace, "inner")
+				return next(ctx, payload)
+			}
+		},
+	)
+
+	payload := &dto.WSPayload{
+		WSPayloadBase: dto.WSPayloadBase{
+			OPCode: dto.WSDispatchEvent, Type: dto.EventGroupAtMessageCreate, EventID: "e1",
+		},
+		RawMessage: []byte(`{"op":0,"t":"GROUP_AT_MESSAGE_CREATE","d":{"content":"hi"}}`),
+	}
+	assert.Equal(t, handlerErr, d.ParseAndHandle(payload))
+	assert.Equal(t, handlerErr, gotErr)
+	assert.Equal(t, []string{"outer:e1", "inner"}, trace)
+
+	payload.RawMessage = []byte(`{"op":0,"t":"GROUP_AT_MESSAGE_CREATE","d":{"content":"panic"}}`)
+	err := d.ParseAndHandle(payload)
+	assert.EqualError(t, err, "panic: boom")
+	assert.Equal(t, err, gotErr)
+}
@@ -0,0 +1,258 @@
+// Package pool 有界的事件处理协程池，相同 key 的任务按提交顺序串行执行，不同 key 的任务并发执行。
+package pool
+
+import (
+	"errors"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/tencent-connect/botgo/dto"
+)
+
+// OverflowPolicy 队列满时的处理策略
+type OverflowPolicy int
+
+const (
+	// OverflowBlock 阻塞等待队列有空位
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest 丢弃队列中最早的任务，放入新任务
+	OverflowDropOldest
+	// OverflowReject 拒绝新任务，Submit 返回 ErrQueueFull
+	OverflowReject
+)
+
+// 默认配置
+const (
+	DefaultWorkers   = 8
+	DefaultQueueSize = 1000
+)
+
+var (
+	// ErrQueueFull 队列已满，任务被拒绝
+	ErrQueueFull = errors.New("pool queue is full")
+	// ErrDropped 队列已满，排队中的任务被 OverflowDropOldest 丢弃
+	ErrDropped = errors.New("pool queue is full, task dropped")
+	// ErrPoolClosed 协程池已经关闭
+	ErrPoolClosed = errors.New("pool is closed")
+)
+
+// KeyFunc 计算事件的保序 key，相同 key 的事件会在同一个 worker 中按顺序执行
+type KeyFunc func(payload *dto.WSPayload) string
+
+// eventKeyPaths 默认按照 群 > 子频道 > 用户 > 频道 的优先级提取事件 key
+var eventKeyPaths = []string{
+	"d.group_openid",
+	"d.group_id",
+	"d.channel_id",
+	"d.author.user_openid",
+	"d.user_openid",
+	"d.openid",
+	"d.author.id",
+	"d.guild_id",
+}
+
+// EventKey 默认的 KeyFunc，从原始事件中提取群 openid，子频道 ID，用户 openid 等作为 key
+// 无法提取 key 的事件返回空字符串，这些事件会在同一个 worker 中按顺序执行
+func EventKey(payload *dto.WSPayload) string {
+	if len(payload.RawMessage) == 0 {
+		return ""
+	}
+	results := gjson.GetManyBytes(payload.RawMessage, eventKeyPaths...)
+	for _, r := range results {
+		if s := r.String(); s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// Config 协程池配置
+type Config struct {
+	Workers   int            // worker 数量，默认 DefaultWorkers
+	QueueSize int            // 每个 worker 的队列长度，默认 DefaultQueueSize
+	Overflow  OverflowPolicy // 队列满时的处理策略，默认阻塞
+	KeyFunc   KeyFunc        // 事件保序 key 的计算方法，默认 EventKey
+	// PanicHandler 任务 panic 时的回调，为空时 panic 会被恢复并忽略
+	PanicHandler func(recovered interface{})
+}
+
+// Stats 协程池的统计信息
+type Stats struct {
+	Workers     int   // worker 数量
+	QueueDepth  []int // 每个 worker 当前排队的任务数
+	TotalQueued int   // 当前排队的任务总数
+	Processed   int64 // 已经执行完成的任务数
+	Dropped     int64 // 由于 OverflowDropOldest 被丢弃的任务数
+	Rejected    int64 // 由于 OverflowReject 被拒绝的任务数
+}
+
+// Pool 按 key 保序的有界协程池
+type Pool struct {
+	// 计数器放在结构体开头，保证 32 位平台上原子操作的 64 位对齐
+	processed int64
+	dropped   int64
+	rejected  int64
+
+	config Config
+	queues []chan job
+	locks  []sync.Mutex // 丢弃最早任务时，保证取出与放入的原子性
+
+	closeLock sync.RWMutex
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// New 创建并启动一个协程池
+func New(config Config) *Pool {
+	if config.Workers <= 0 {
+		config.Workers = DefaultWorkers
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = DefaultQueueSize
+	}
+	if config.KeyFunc == nil {
+		config.KeyFunc = EventKey
+	}
+	p := &Pool{
+		config: config,
+		queues: make([]chan job, config.Workers),
+		locks:  make([]sync.Mutex, config.Workers),
+	}
+	for i := range p.queues {
+		p.queues[i] = make(chan job, config.QueueSize)
+		p.wg.Add(1)
+		go p.work(p.queues[i])
+	}
+	return p
+}
+
+// Key 计算事件的保序 key
+func (p *Pool) Key(payload *dto.WSPayload) string {
+	return p.config.KeyFunc(payload)
+}
+
+// job 排队中的任务，onDrop 在任务被 OverflowDropOldest 丢弃时调用
+type job struct {
+	task   func()
+	onDrop func()
+}
+
+// Submit 提交任务，相同 key 的任务按照提交顺序执行
+func (p *Pool) Submit(key string, task func()) error {
+	return p.SubmitWithDrop(key, task, nil)
+}
+
+// SubmitWithDrop 与 Submit 相同，任务由于 OverflowDropOldest 被丢弃时调用 onDrop，用于释放任务占用的资源
+// onDrop 在提交新任务的协程中执行，不应该阻塞
+func (p *Pool) SubmitWithDrop(key string, task func(), onDrop func()) error {
+	p.closeLock.RLock()
+	defer p.closeLock.RUnlock()
+	if p.closed {
+		return ErrPoolClosed
+	}
+	i := p.index(key)
+	queue := p.queues[i]
+	j := job{task: task, onDrop: onDrop}
+	switch p.config.Overflow {
+	case OverflowReject:
+		select {
+		case queue <- j:
+			return nil
+		default:
+			atomic.AddInt64(&p.rejected, 1)
+			return ErrQueueFull
+		}
+	case OverflowDropOldest:
+		dropped := p.dropOldest(i, j)
+		for _, d := range dropped {
+			if d.onDrop != nil {
+				d.onDrop()
+			}
+		}
+		return nil
+	default:
+		queue <- j
+		return nil
+	}
+}
+
+// dropOldest 放入任务，队列满时丢弃最早的任务，返回被丢弃的任务
+func (p *Pool) dropOldest(i int, j job) []job {
+	p.locks[i].Lock()
+	defer p.locks[i].Unlock()
+	queue := p.queues[i]
+	var dropped []job
+	for {
+		select {
+		case queue <- j:
+			return dropped
+		default:
+		}
+		select {
+		case d := <-queue:
+			atomic.AddInt64(&p.dropped, 1)
+			dropped = append(dropped, d)
+		default:
+		}
+	}
+}
+
+// Stats 获取协程池的统计信息
+func (p *Pool) Stats() Stats {
+	s := Stats{
+		Workers:    len(p.queues),
+		QueueDepth: make([]int, len(p.queues)),
+		Processed:  atomic.LoadInt64(&p.processed),
+		Dropped:    atomic.LoadInt64(&p.dropped),
+		Rejected:   atomic.LoadInt64(&p.rejected),
+	}
+	for i, q := range p.queues {
+		s.QueueDepth[i] = len(q)
+		s.TotalQueued += len(q)
+	}
+	return s
+}
+
+// Close 关闭协程池，不再接收新任务，等待已经排队的任务执行完成后返回
+func (p *Pool) Close() {
+	p.closeLock.Lock()
+	if p.closed {
+		p.closeLock.Unlock()
+		return
+	}
+	p.closed = true
+	for _, q := range p.queues {
+		close(q)
+	}
+	p.closeLock.Unlock()
+	p.wg.Wait()
+}
+
+func (p *Pool) index(key string) int {
+	if len(p.queues) == 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(len(p.queues)))
+}
+
+func (p *Pool) work(queue chan job) {
+	defer p.wg.Done()
+	for j := range queue {
+		p.run(j.task)
+		atomic.AddInt64(&p.processed, 1)
+	}
+}
+
+func (p *Pool) run(task func()) {
+	defer func() {
+		if err := recover(); err != nil && p.config.PanicHandler != nil {
+			p.config.PanicHandler(err)
+		}
+	}()
+	task()
+}
@@ -0,0 +1,105 @@
+package pool
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tencent-connect/botgo/dto"
+)
+
+func TestEventKey(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{"group", `{"d":{"group_openid":"g1","author":{"member_openid":"m1"}}}`, "g1"},
+		{"channel", `{"d":{"channel_id":"c1","guild_id":"gd1","author":{"id":"u1"}}}`, "c1"},
+		{"c2c", `{"d":{"author":{"user_openid":"u1"}}}`, "u1"},
+		{"guild", `{"d":{"guild_id":"gd1"}}`, "gd1"},
+		{"none", `{"d":{}}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, EventKey(&dto.WSPayload{RawMessage: []byte(tt.raw)}))
+		})
+	}
+}
+
+func TestPool_KeepOrderPerKey(t *testing.T) {
+	p := New(Config{Workers: 4, QueueSize: 10})
+	var lock sync.Mutex
+	got := map[string][]int{}
+	for i := 0; i < 100; i++ {
+		key := []string{"a", "b", "c"}[i%3]
+		i := i
+		assert.Nil(t, p.Submit(key, func() {
+			lock.Lock()
+			defer lock.Unlock()
+			got[key] = append(got[key], i)
+		}))
+	}
+	p.Close()
+	for key, seq := range got {
+		for j := 1; j < len(seq); j++ {
+			assert.Less(t, seq[j-1], seq[j], key)
+		}
+	}
+	assert.Equal(t, int64(100), p.Stats().Processed)
+	assert.Equal(t, ErrPoolClosed, p.Submit("a", func() {}))
+}
+
+func TestPool_Overflow(t *testing.T) {
+	block := make(chan struct{})
+	started := make(chan struct{})
+	first := func() {
+		close(started)
+		<-block
+	}
+
+	t.Run("reject", func(t *testing.T) {
+		p := New(Config{Workers: 1, QueueSize: 1, Overflow: OverflowReject})
+		started = make(chan struct{})
+		block = make(chan struct{})
+		assert.Nil(t, p.Submit("k", first))
+		<-started
+		assert.Nil(t, p.Submit("k", func() {}))
+		assert.Equal(t, ErrQueueFull, p.Submit("k", func() {}))
+		assert.Equal(t, 1, p.Stats().TotalQueued)
+		assert.Equal(t, int64(1), p.Stats().Rejected)
+		close(block)
+		p.Close()
+	})
+
+	t.Run("drop oldest", func(t *testing.T) {
+		p := New(Config{Workers: 1, QueueSize: 1, Overflow: OverflowDropOldest})
+		started = make(chan struct{})
+		block = make(chan struct{})
+		var ran []int
+		assert.Nil(t, p.Submit("k", first))
+		<-started
+		assert.Nil(t, p.Submit("k", func() { ran = append(ran, 1) }))
+		assert.Nil(t, p.Submit("k", func() { ran = append(ran, 2) }))
+		close(block)
+		p.Close()
+		assert.Equal(t, []int{2}, ran)
+		assert.Equal(t, int64(1), p.Stats().Dropped)
+	})
+
+	t.Run("drop callback", func(t *testing.T) {
+		p := New(Config{Workers: 1, QueueSize: 1, Overflow: OverflowDropOldest})
+		started = make(chan struct{})
+		block = make(chan struct{})
+		var dropped []int
+		assert.Nil(t, p.Submit("k", first))
+		<-started
+		for i := 1; i <= 3; i++ {
+			i := i
+			assert.Nil(t, p.SubmitWithDrop("k", func() {}, func() { dropped = append(dropped, i) }))
+		}
+		assert.Equal(t, []int{1, 2}, dropped)
+		close(block)
+		p.Close()
+	})
+}
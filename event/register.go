@@ -1,11 +1,16 @@
 package event
 
 import (
+	"context"
+
 	"github.com/tencent-connect/botgo/dto"
 )
 
-// DefaultHandlers 默认的 handler 结构，管理所有支持的 handler 类型
-var DefaultHandlers struct {
+// DefaultHandlers 默认的 handler 集合，即默认分发器 DefaultDispatcher 所使用的 handler
+var DefaultHandlers Handlers
+
+// Handlers handler 集合，管理所有支持的 handler 类型
+type Handlers struct {
 	Ready       ReadyHandler
 	ErrorNotify ErrorNotifyHandler
 	Plain       PlainEventHandler
@@ -38,145 +43,305 @@ var DefaultHandlers struct {
 	C2CFriend          C2CFriendEventHandler
 
 	EnterAIO EnterAIOEventHandler
+
+	// 携带 context 的 handler，与上面同名的 handler 同时注册时，优先使用携带 context 的版本
+	ReadyCtx       ReadyCtxHandler
+	ErrorNotifyCtx ErrorNotifyCtxHandler
+	PlainCtx       PlainEventCtxHandler
+
+	GuildCtx       GuildEventCtxHandler
+	GuildMemberCtx GuildMemberEventCtxHandler
+	ChannelCtx     ChannelEventCtxHandler
+
+	MessageCtx             MessageEventCtxHandler
+	MessageReactionCtx     MessageReactionEventCtxHandler
+	ATMessageCtx           ATMessageEventCtxHandler
+	DirectMessageCtx       DirectMessageEventCtxHandler
+	MessageAuditCtx        MessageAuditEventCtxHandler
+	MessageDeleteCtx       MessageDeleteEventCtxHandler
+	PublicMessageDeleteCtx PublicMessageDeleteEventCtxHandler
+	DirectMessageDeleteCtx DirectMessageDeleteEventCtxHandler
+
+	AudioCtx AudioEventCtxHandler
+
+	ThreadCtx     ThreadEventCtxHandler
+	PostCtx       PostEventCtxHandler
+	ReplyCtx      ReplyEventCtxHandler
+	ForumAuditCtx ForumAuditEventCtxHandler
+
+	InteractionCtx InteractionEventCtxHandler
+
+	GroupATMessageCtx     GroupATMessageEventCtxHandler
+	C2CMessageCtx         C2CMessageEventCtxHandler
+	SubscribeMsgStatusCtx SubscribeMsgStatusEventCtxHandler
+	C2CFriendCtx          C2CFriendEventCtxHandler
+
+	EnterAIOCtx EnterAIOEventCtxHandler
 }
 
 // ReadyHandler 可以处理 ws 的 ready 事件
 type ReadyHandler func(event *dto.WSPayload, data *dto.WSReadyData)
 
+// ReadyCtxHandler 可以处理 ws 的 ready 事件，携带 context
+type ReadyCtxHandler func(ctx context.Context, event *dto.WSPayload, data *dto.WSReadyData)
+
 // ErrorNotifyHandler 当 ws 连接发生错误的时候，会回调，方便使用方监控相关错误
 // 比如 reconnect invalidSession 等错误，错误可以转换为 bot.Err
 type ErrorNotifyHandler func(err error)
 
+// ErrorNotifyCtxHandler 当 ws 连接发生错误的时候，会回调，方便使用方监控相关错误，携带 context
+type ErrorNotifyCtxHandler func(ctx context.Context, err error)
+
 // PlainEventHandler 透传handler
 type PlainEventHandler func(event *dto.WSPayload, message []byte) error
 
+// PlainEventCtxHandler 透传handler，携带 context
+type PlainEventCtxHandler func(ctx context.Context, event *dto.WSPayload, message []byte) error
+
 // GuildEventHandler 频道事件handler
 type GuildEventHandler func(event *dto.WSPayload, data *dto.WSGuildData) error
 
+// GuildEventCtxHandler 频道事件handler，携带 context
+type GuildEventCtxHandler func(ctx context.Context, event *dto.WSPayload, data *dto.WSGuildData) error
+
 // GuildMemberEventHandler 频道成员事件 handler
 type GuildMemberEventHandler func(event *dto.WSPayload, data *dto.WSGuildMemberData) error
 
+// GuildMemberEventCtxHandler 频道成员事件 handler，携带 context
+type GuildMemberEventCtxHandler func(ctx context.Context, event *dto.WSPayload, data *dto.WSGuildMemberData) error
+
 // ChannelEventHandler 子频道事件 handler
 type ChannelEventHandler func(event *dto.WSPayload, data *dto.WSChannelData) error
 
+// ChannelEventCtxHandler 子频道事件 handler，携带 context
+type ChannelEventCtxHandler func(ctx context.Context, event *dto.WSPayload, data *dto.WSChannelData) error
+
 // MessageEventHandler 消息事件 handler
 type MessageEventHandler func(event *dto.WSPayload, data *dto.WSMessageData) error
 
+// MessageEventCtxHandler 消息事件 handler，携带 context
+type MessageEventCtxHandler func(ctx context.Context, event *dto.WSPayload, data *dto.WSMessageData) error
+
 // MessageDeleteEventHandler 消息事件 handler
 type MessageDeleteEventHandler func(event *dto.WSPayload, data *dto.WSMessageDeleteData) error
 
+// MessageDeleteEventCtxHandler 消息事件 handler，携带 context
+type MessageDeleteEventCtxHandler func(ctx context.Context, event *dto.WSPayload, data *dto.WSMessageDeleteData) error
+
 // PublicMessageDeleteEventHandler 消息事件 handler
 type PublicMessageDeleteEventHandler func(event *dto.WSPayload, data *dto.WSPublicMessageDeleteData) error
 
+// PublicMessageDeleteEventCtxHandler 消息事件 handler，携带 context
+type PublicMessageDeleteEventCtxHandler func(
+	ctx context.Context, event *dto.WSPayload, data *dto.WSPublicMessageDeleteData,
+) error
+
 // DirectMessageDeleteEventHandler 消息事件 handler
 type DirectMessageDeleteEventHandler func(event *dto.WSPayload, data *dto.WSDirectMessageDeleteData) error
 
+// DirectMessageDeleteEventCtxHandler 消息事件 handler，携带 context
+type DirectMessageDeleteEventCtxHandler func(
+	ctx context.Context, event *dto.WSPayload, data *dto.WSDirectMessageDeleteData,
+) error
+
 // MessageReactionEventHandler 表情表态事件 handler
 type MessageReactionEventHandler func(event *dto.WSPayload, data *dto.WSMessageReactionData) error
 
+// MessageReactionEventCtxHandler 表情表态事件 handler，携带 context
+type MessageReactionEventCtxHandler func(
+	ctx context.Context, event *dto.WSPayload, data *dto.WSMessageReactionData,
+) error
+
 // ATMessageEventHandler at 机器人消息事件 handler
 type ATMessageEventHandler func(event *dto.WSPayload, data *dto.WSATMessageData) error
 
+// ATMessageEventCtxHandler at 机器人消息事件 handler，携带 context
+type ATMessageEventCtxHandler func(ctx context.Context, event *dto.WSPayload, data *dto.WSATMessageData) error
+
 // DirectMessageEventHandler 私信消息事件 handler
 type DirectMessageEventHandler func(event *dto.WSPayload, data *dto.WSDirectMessageData) error
 
+// DirectMessageEventCtxHandler 私信消息事件 handler，携带 context
+type DirectMessageEventCtxHandler func(ctx context.Context, event *dto.WSPayload, data *dto.WSDirectMessageData) error
+
 // AudioEventHandler 音频机器人事件 handler
 type AudioEventHandler func(event *dto.WSPayload, data *dto.WSAudioData) error
 
+// AudioEventCtxHandler 音频机器人事件 handler，携带 context
+type AudioEventCtxHandler func(ctx context.Context, event *dto.WSPayload, data *dto.WSAudioData) error
+
 // MessageAuditEventHandler 消息审核事件 handler
 type MessageAuditEventHandler func(event *dto.WSPayload, data *dto.WSMessageAuditData) error
 
+// MessageAuditEventCtxHandler 消息审核事件 handler，携带 context
+type MessageAuditEventCtxHandler func(ctx context.Context, event *dto.WSPayload, data *dto.WSMessageAuditData) error
+
 // ThreadEventHandler 论坛主题事件 handler
 type ThreadEventHandler func(event *dto.WSPayload, data *dto.WSThreadData) error
 
+// ThreadEventCtxHandler 论坛主题事件 handler，携带 context
+type ThreadEventCtxHandler func(ctx context.Context, event *dto.WSPayload, data *dto.WSThreadData) error
+
 // PostEventHandler 论坛回帖事件 handler
 type PostEventHandler func(event *dto.WSPayload, data *dto.WSPostData) error
 
+// PostEventCtxHandler 论坛回帖事件 handler，携带 context
+type PostEventCtxHandler func(ctx context.Context, event *dto.WSPayload, data *dto.WSPostData) error
+
 // ReplyEventHandler 论坛帖子回复事件 handler
 type ReplyEventHandler func(event *dto.WSPayload, data *dto.WSReplyData) error
 
+// ReplyEventCtxHandler 论坛帖子回复事件 handler，携带 context
+type ReplyEventCtxHandler func(ctx context.Context, event *dto.WSPayload, data *dto.WSReplyData) error
+
 // ForumAuditEventHandler 论坛帖子审核事件 handler
 type ForumAuditEventHandler func(event *dto.WSPayload, data *dto.WSForumAuditData) error
 
+// ForumAuditEventCtxHandler 论坛帖子审核事件 handler，携带 context
+type ForumAuditEventCtxHandler func(ctx context.Context, event *dto.WSPayload, data *dto.WSForumAuditData) error
+
 // InteractionEventHandler 互动事件 handler
 type InteractionEventHandler func(event *dto.WSPayload, data *dto.WSInteractionData) error
 
+// InteractionEventCtxHandler 互动事件 handler，携带 context
+type InteractionEventCtxHandler func(ctx context.Context, event *dto.WSPayload, data *dto.WSInteractionData) error
+
 // ***************** 群消息/C2C消息  *****************
 
 // GroupATMessageEventHandler 群中at机器人消息事件 handler
 type GroupATMessageEventHandler func(event *dto.WSPayload, data *dto.WSGroupATMessageData) error
 
+// GroupATMessageEventCtxHandler 群中at机器人消息事件 handler，携带 context
+type GroupATMessageEventCtxHandler func(ctx context.Context, event *dto.WSPayload, data *dto.WSGroupATMessageData) error
+
 // C2CMessageEventHandler 机器人消息事件 handler
 type C2CMessageEventHandler func(event *dto.WSPayload, data *dto.WSC2CMessageData) error
 
+// C2CMessageEventCtxHandler 机器人消息事件 handler，携带 context
+type C2CMessageEventCtxHandler func(ctx context.Context, event *dto.WSPayload, data *dto.WSC2CMessageData) error
+
 // ***************** C2C 添加/删除好友 *******************************
 
 // C2CFriendEventHandler C2C 好友事件 handler
 type C2CFriendEventHandler func(event *dto.WSPayload, data *dto.WSC2CFriendData) error
 
+// C2CFriendEventCtxHandler C2C 好友事件 handler，携带 context
+type C2CFriendEventCtxHandler func(ctx context.Context, event *dto.WSPayload, data *dto.WSC2CFriendData) error
+
 // ************************************************
 
 // SubscribeMsgStatusEventHandler 订阅消息模板授权状态变更事件 handler
 type SubscribeMsgStatusEventHandler func(event *dto.WSPayload, data *dto.WSSubscribeMsgStatus) error
 
+// SubscribeMsgStatusEventCtxHandler 订阅消息模板授权状态变更事件 handler，携带 context
+type SubscribeMsgStatusEventCtxHandler func(
+	ctx context.Context, event *dto.WSPayload, data *dto.WSSubscribeMsgStatus,
+) error
+
 // EnterAIOEventHandler 进入AIO事件 handler
 type EnterAIOEventHandler func(event *dto.WSPayload, data *dto.WSEnterAIOData) error
 
-// RegisterHandlers 注册事件回调，并返回 intent 用于 websocket 的鉴权
+// EnterAIOEventCtxHandler 进入AIO事件 handler，携带 context
+type EnterAIOEventCtxHandler func(ctx context.Context, event *dto.WSPayload, data *dto.WSEnterAIOData) error
+
+// RegisterHandlers 注册事件回调到默认分发器，并返回 intent 用于 websocket 的鉴权
 func RegisterHandlers(handlers ...interface{}) dto.Intent {
+	return DefaultDispatcher.RegisterHandlers(handlers...)
+}
+
+// RegisterHandlers 注册事件回调到当前分发器，并返回 intent 用于 websocket 的鉴权
+func (d *Dispatcher) RegisterHandlers(handlers ...interface{}) dto.Intent {
 	var i dto.Intent
 	for _, h := range handlers {
 		switch handle := h.(type) {
 		case ReadyHandler:
-			DefaultHandlers.Ready = handle
+			d.handlers.Ready = handle
+		case ReadyCtxHandler:
+			d.handlers.ReadyCtx = handle
 		case ErrorNotifyHandler:
-			DefaultHandlers.ErrorNotify = handle
+			d.handlers.ErrorNotify = handle
+		case ErrorNotifyCtxHandler:
+			d.handlers.ErrorNotifyCtx = handle
 		case PlainEventHandler:
-			DefaultHandlers.Plain = handle
+			d.handlers.Plain = handle
+		case PlainEventCtxHandler:
+			d.handlers.PlainCtx = handle
 		case AudioEventHandler:
-			DefaultHandlers.Audio = handle
+			d.handlers.Audio = handle
+			i = i | dto.EventToIntent(
+				dto.EventAudioStart, dto.EventAudioFinish,
+				dto.EventAudioOnMic, dto.EventAudioOffMic,
+			)
+		case AudioEventCtxHandler:
+			d.handlers.AudioCtx = handle
 			i = i | dto.EventToIntent(
 				dto.EventAudioStart, dto.EventAudioFinish,
 				dto.EventAudioOnMic, dto.EventAudioOffMic,
 			)
 		case InteractionEventHandler:
-			DefaultHandlers.Interaction = handle
+			d.handlers.Interaction = handle
+			i = i | dto.EventToIntent(dto.EventInteractionCreate)
+		case InteractionEventCtxHandler:
+			d.handlers.InteractionCtx = handle
 			i = i | dto.EventToIntent(dto.EventInteractionCreate)
 		case SubscribeMsgStatusEventHandler:
-			DefaultHandlers.SubscribeMsgStatus = handle
+			d.handlers.SubscribeMsgStatus = handle
+			i = i | dto.EventToIntent(dto.EventSubscribeMsgStatus)
+		case SubscribeMsgStatusEventCtxHandler:
+			d.handlers.SubscribeMsgStatusCtx = handle
 			i = i | dto.EventToIntent(dto.EventSubscribeMsgStatus)
 		case C2CFriendEventHandler:
-			DefaultHandlers.C2CFriend = handle
+			d.handlers.C2CFriend = handle
+			i = i | dto.EventToIntent(dto.EventC2CFriendAdd)
+		case C2CFriendEventCtxHandler:
+			d.handlers.C2CFriendCtx = handle
 			i = i | dto.EventToIntent(dto.EventC2CFriendAdd)
 		case EnterAIOEventHandler:
-			DefaultHandlers.EnterAIO = handle
+			d.handlers.EnterAIO = handle
+			i = i | dto.EventToIntent(dto.EventEnterAIO)
+		case EnterAIOEventCtxHandler:
+			d.handlers.EnterAIOCtx = handle
 			i = i | dto.EventToIntent(dto.EventEnterAIO)
 		default:
 		}
 	}
-	i = i | registerRelationHandlers(i, handlers...)
-	i = i | registerMessageHandlers(i, handlers...)
-	i = i | registerForumHandlers(i, handlers...)
+	i = i | d.registerRelationHandlers(i, handlers...)
+	i = i | d.registerMessageHandlers(i, handlers...)
+	i = i | d.registerForumHandlers(i, handlers...)
 
 	return i
 }
 
-func registerForumHandlers(i dto.Intent, handlers ...interface{}) dto.Intent {
+func (d *Dispatcher) registerForumHandlers(i dto.Intent, handlers ...interface{}) dto.Intent {
 	for _, h := range handlers {
 		switch handle := h.(type) {
 		case ThreadEventHandler:
-			DefaultHandlers.Thread = handle
+			d.handlers.Thread = handle
+			i = i | dto.EventToIntent(
+				dto.EventForumThreadCreate, dto.EventForumThreadUpdate, dto.EventForumThreadDelete,
+			)
+		case ThreadEventCtxHandler:
+			d.handlers.ThreadCtx = handle
 			i = i | dto.EventToIntent(
 				dto.EventForumThreadCreate, dto.EventForumThreadUpdate, dto.EventForumThreadDelete,
 			)
 		case PostEventHandler:
-			DefaultHandlers.Post = handle
+			d.handlers.Post = handle
+			i = i | dto.EventToIntent(dto.EventForumPostCreate, dto.EventForumPostDelete)
+		case PostEventCtxHandler:
+			d.handlers.PostCtx = handle
 			i = i | dto.EventToIntent(dto.EventForumPostCreate, dto.EventForumPostDelete)
 		case ReplyEventHandler:
-			DefaultHandlers.Reply = handle
+			d.handlers.Reply = handle
+			i = i | dto.EventToIntent(dto.EventForumReplyCreate, dto.EventForumReplyDelete)
+		case ReplyEventCtxHandler:
+			d.handlers.ReplyCtx = handle
 			i = i | dto.EventToIntent(dto.EventForumReplyCreate, dto.EventForumReplyDelete)
 		case ForumAuditEventHandler:
-			DefaultHandlers.ForumAudit = handle
+			d.handlers.ForumAudit = handle
+			i = i | dto.EventToIntent(dto.EventForumAuditResult)
+		case ForumAuditEventCtxHandler:
+			d.handlers.ForumAuditCtx = handle
 			i = i | dto.EventToIntent(dto.EventForumAuditResult)
 		default:
 		}
@@ -185,17 +350,26 @@ func registerForumHandlers(i dto.Intent, handlers ...interface{}) dto.Intent {
 }
 
 // registerRelationHandlers 注册频道关系链相关handlers
-func registerRelationHandlers(i dto.Intent, handlers ...interface{}) dto.Intent {
+func (d *Dispatcher) registerRelationHandlers(i dto.Intent, handlers ...interface{}) dto.Intent {
 	for _, h := range handlers {
 		switch handle := h.(type) {
 		case GuildEventHandler:
-			DefaultHandlers.Guild = handle
+			d.handlers.Guild = handle
+			i = i | dto.EventToIntent(dto.EventGuildCreate, dto.EventGuildDelete, dto.EventGuildUpdate)
+		case GuildEventCtxHandler:
+			d.handlers.GuildCtx = handle
 			i = i | dto.EventToIntent(dto.EventGuildCreate, dto.EventGuildDelete, dto.EventGuildUpdate)
 		case GuildMemberEventHandler:
-			DefaultHandlers.GuildMember = handle
+			d.handlers.GuildMember = handle
+			i = i | dto.EventToIntent(dto.EventGuildMemberAdd, dto.EventGuildMemberRemove, dto.EventGuildMemberUpdate)
+		case GuildMemberEventCtxHandler:
+			d.handlers.GuildMemberCtx = handle
 			i = i | dto.EventToIntent(dto.EventGuildMemberAdd, dto.EventGuildMemberRemove, dto.EventGuildMemberUpdate)
 		case ChannelEventHandler:
-			DefaultHandlers.Channel = handle
+			d.handlers.Channel = handle
+			i = i | dto.EventToIntent(dto.EventChannelCreate, dto.EventChannelDelete, dto.EventChannelUpdate)
+		case ChannelEventCtxHandler:
+			d.handlers.ChannelCtx = handle
 			i = i | dto.EventToIntent(dto.EventChannelCreate, dto.EventChannelDelete, dto.EventChannelUpdate)
 		default:
 		}
@@ -204,38 +378,68 @@ func registerRelationHandlers(i dto.Intent, handlers ...interface{}) dto.Intent
 }
 
 // registerMessageHandlers 注册消息相关的 handler
-func registerMessageHandlers(i dto.Intent, handlers ...interface{}) dto.Intent {
+func (d *Dispatcher) registerMessageHandlers(i dto.Intent, handlers ...interface{}) dto.Intent {
 	for _, h := range handlers {
 		switch handle := h.(type) {
 		case MessageEventHandler:
-			DefaultHandlers.Message = handle
+			d.handlers.Message = handle
+			i = i | dto.EventToIntent(dto.EventMessageCreate)
+		case MessageEventCtxHandler:
+			d.handlers.MessageCtx = handle
 			i = i | dto.EventToIntent(dto.EventMessageCreate)
 		case ATMessageEventHandler:
-			DefaultHandlers.ATMessage = handle
+			d.handlers.ATMessage = handle
+			i = i | dto.EventToIntent(dto.EventAtMessageCreate)
+		case ATMessageEventCtxHandler:
+			d.handlers.ATMessageCtx = handle
 			i = i | dto.EventToIntent(dto.EventAtMessageCreate)
 		case DirectMessageEventHandler:
-			DefaultHandlers.DirectMessage = handle
+			d.handlers.DirectMessage = handle
+			i = i | dto.EventToIntent(dto.EventDirectMessageCreate)
+		case DirectMessageEventCtxHandler:
+			d.handlers.DirectMessageCtx = handle
 			i = i | dto.EventToIntent(dto.EventDirectMessageCreate)
 		case MessageDeleteEventHandler:
-			DefaultHandlers.MessageDelete = handle
+			d.handlers.MessageDelete = handle
+			i = i | dto.EventToIntent(dto.EventMessageDelete)
+		case MessageDeleteEventCtxHandler:
+			d.handlers.MessageDeleteCtx = handle
 			i = i | dto.EventToIntent(dto.EventMessageDelete)
 		case PublicMessageDeleteEventHandler:
-			DefaultHandlers.PublicMessageDelete = handle
+			d.handlers.PublicMessageDelete = handle
+			i = i | dto.EventToIntent(dto.EventPublicMessageDelete)
+		case PublicMessageDeleteEventCtxHandler:
+			d.handlers.PublicMessageDeleteCtx = handle
 			i = i | dto.EventToIntent(dto.EventPublicMessageDelete)
 		case DirectMessageDeleteEventHandler:
-			DefaultHandlers.DirectMessageDelete = handle
+			d.handlers.DirectMessageDelete = handle
+			i = i | dto.EventToIntent(dto.EventDirectMessageDelete)
+		case DirectMessageDeleteEventCtxHandler:
+			d.handlers.DirectMessageDeleteCtx = handle
 			i = i | dto.EventToIntent(dto.EventDirectMessageDelete)
 		case MessageReactionEventHandler:
-			DefaultHandlers.MessageReaction = handle
+			d.handlers.MessageReaction = handle
+			i = i | dto.EventToIntent(dto.EventMessageReactionAdd, dto.EventMessageReactionRemove)
+		case MessageReactionEventCtxHandler:
+			d.handlers.MessageReactionCtx = handle
 			i = i | dto.EventToIntent(dto.EventMessageReactionAdd, dto.EventMessageReactionRemove)
 		case MessageAuditEventHandler:
-			DefaultHandlers.MessageAudit = handle
+			d.handlers.MessageAudit = handle
+			i = i | dto.EventToIntent(dto.EventMessageAuditPass, dto.EventMessageAuditReject)
+		case MessageAuditEventCtxHandler:
+			d.handlers.MessageAuditCtx = handle
 			i = i | dto.EventToIntent(dto.EventMessageAuditPass, dto.EventMessageAuditReject)
 		case GroupATMessageEventHandler:
-			DefaultHandlers.GroupATMessage = handle
+			d.handlers.GroupATMessage = handle
+			i = i | dto.EventToIntent(dto.EventGroupAtMessageCreate)
+		case GroupATMessageEventCtxHandler:
+			d.handlers.GroupATMessageCtx = handle
 			i = i | dto.EventToIntent(dto.EventGroupAtMessageCreate)
 		case C2CMessageEventHandler:
-			DefaultHandlers.C2CMessage = handle
+			d.handlers.C2CMessage = handle
+			i = i | dto.EventToIntent(dto.EventC2CMessageCreate)
+		case C2CMessageEventCtxHandler:
+			d.handlers.C2CMessageCtx = handle
 			i = i | dto.EventToIntent(dto.EventC2CMessageCreate)
 		default:
 		}
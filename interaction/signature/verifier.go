@@ -0,0 +1,57 @@
+package signature
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"net/http"
+)
+
+// Verifier 签名验证器，创建时根据 secret 生成密钥，避免每次验证都重新生成
+// 支持同时使用多个 secret，用于 secret 轮换期间新旧 secret 都能通过验证，可以被多个协程并发使用
+type Verifier struct {
+	keys []*ed25519Key
+}
+
+// NewVerifier 创建签名验证器，secrets 中的第一个为当前使用的 secret，用于生成签名，其余的只用于验证
+func NewVerifier(secrets ...string) (*Verifier, error) {
+	if len(secrets) == 0 {
+		return nil, errors.New("secret invalid")
+	}
+	v := &Verifier{keys: make([]*ed25519Key, 0, len(secrets))}
+	for _, secret := range secrets {
+		key, err := genKey(secret)
+		if err != nil {
+			return nil, err
+		}
+		v.keys = append(v.keys, key)
+	}
+	return v, nil
+}
+
+// Verify 验证签名，任意一个 secret 验证通过即可，需要传入 http 头，httpBody
+func (v *Verifier) Verify(header http.Header, httpBody []byte) (bool, error) {
+	sigBuffer, err := decodeSigBuffer(header.Get(HeaderSig))
+	if err != nil {
+		return false, err
+	}
+	content, err := genOriginalContent(header.Get(HeaderTimestamp), httpBody)
+	if err != nil {
+		return false, err
+	}
+	for _, key := range v.keys {
+		if ed25519.Verify(key.PublicKey, content, sigBuffer) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Generate 使用当前的 secret 生成签名
+func (v *Verifier) Generate(header http.Header, httpBody []byte) (string, error) {
+	content, err := genOriginalContent(header.Get(HeaderTimestamp), httpBody)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(ed25519.Sign(v.keys[0].PrivateKey, content)), nil
+}
@@ -0,0 +1,89 @@
+package signature
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	benchHeader = http.Header{
+		"X-Signature-Ed25519":   {"e949b5b94ef4103df903fb031d1d16e358db3db83e79e117edd404c8508be3ce8a76d7bad1bed353194c126a1a5915b4ad8b5288c1191cc53a12acffccd82004"},
+		"X-Signature-Timestamp": {"1728981195"},
+	}
+	benchBody   = []byte(`{"id":"ROBOT1.0_veoihSEXDc8Q.g-6eLpNIa11bH8MisOjn-m-LKxCPntMk6exUXgcWCGpVO7L2QKTNZzjZzFFDSbiOFcqAPWyVA!!","content":"哦一下","timestamp":"2024-10-15T16:33:15+08:00","author":{"id":"675860273","user_openid":"675860273"}}`)
+	benchSecret = "123456abcdef"
+)
+
+func TestVerifier(t *testing.T) {
+	t.Run("rotation", func(t *testing.T) {
+		v, err := NewVerifier("new-secret", benchSecret)
+		assert.Nil(t, err)
+		pass, err := v.Verify(benchHeader, benchBody)
+		assert.Nil(t, err)
+		assert.True(t, pass)
+
+		v, _ = NewVerifier("new-secret")
+		pass, err = v.Verify(benchHeader, benchBody)
+		assert.Nil(t, err)
+		assert.False(t, pass)
+	})
+	t.Run("generate", func(t *testing.T) {
+		v, _ := NewVerifier(benchSecret, "old-secret")
+		sig, err := v.Generate(benchHeader, benchBody)
+		assert.Nil(t, err)
+		assert.Equal(t, benchHeader.Get(HeaderSig), sig)
+	})
+	t.Run("invalid", func(t *testing.T) {
+		_, err := NewVerifier()
+		assert.NotNil(t, err)
+		_, err = NewVerifier(benchSecret, "")
+		assert.NotNil(t, err)
+		v, _ := NewVerifier(benchSecret)
+		_, err = v.Verify(http.Header{}, benchBody)
+		assert.NotNil(t, err)
+	})
+}
+
+func BenchmarkVerify(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if pass, _ := Verify(benchSecret, benchHeader, benchBody); !pass {
+			b.Fatal("verify failed")
+		}
+	}
+}
+
+func BenchmarkVerifier_Verify(b *testing.B) {
+	v, err := NewVerifier(benchSecret)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if pass, _ := v.Verify(benchHeader, benchBody); !pass {
+			b.Fatal("verify failed")
+		}
+	}
+}
+
+func BenchmarkGenerate(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if _, err := Generate(benchSecret, benchHeader, benchBody); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkVerifier_Generate(b *testing.B) {
+	v, err := NewVerifier(benchSecret)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := v.Generate(benchHeader, benchBody); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tencent-connect/botgo/dto"
+	"github.com/tencent-connect/botgo/event/pool"
+	"github.com/tencent-connect/botgo/log"
+)
+
+// DeadLetterHandler 异步处理失败的事件，err 为 handler 返回的错误或者 panic 的信息，事件被协程池丢弃时为 pool.ErrDropped
+// 事件已经回包成功，平台不会重试，需要在这里记录或者自行重新投递
+type DeadLetterHandler func(payload *dto.WSPayload, err error)
+
+// submit 将事件投递到协程池中处理，投递失败时回包失败，由平台重试
+// 已经回包的事件被 pool.OverflowDropOldest 丢弃时，交给 deadLetter 处理
+func (s *Server) submit(payload *dto.WSPayload, traceID string) error {
+	err := s.pool.SubmitWithDrop(s.pool.Key(payload), func() {
+		s.handleAsync(payload, traceID)
+	}, func() {
+		log.Errorf("event dropped by pool, type: %s, id: %s, traceID: %s", payload.Type, payload.EventID, traceID)
+		if s.deadLetter != nil {
+			s.deadLetter(payload, pool.ErrDropped)
+		}
+	})
+	if err != nil {
+		log.Errorf("submit event to pool failed, %v, type: %s, id: %s, traceID: %s",
+			err, payload.Type, payload.EventID, traceID)
+	}
+	return err
+}
+
+func (s *Server) handleAsync(payload *dto.WSPayload, traceID string) {
+	var err error
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panic: %v", r)
+		}
+		if err == nil {
+			return
+		}
+		log.Errorf("async parseAndHandle failed, %v, traceID:%s, type: %s, id: %s",
+			err, traceID, payload.Type, payload.EventID)
+		if s.deadLetter != nil {
+			s.deadLetter(payload, err)
+		}
+	}()
+	// 请求的 context 在回包之后就会被取消，不能用于后台处理
+	err = s.dispatcher.ParseAndHandleContext(context.Background(), payload)
+}
@@ -0,0 +1,104 @@
+package webhook
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tencent-connect/botgo/dto"
+	"github.com/tencent-connect/botgo/event"
+	"github.com/tencent-connect/botgo/event/pool"
+)
+
+func TestServer_AsyncDispatch(t *testing.T) {
+	dispatcher := event.NewDispatcher()
+	started, release := make(chan struct{}, 4), make(chan struct{})
+	handlerErr := errors.New("handler failed")
+	dispatcher.RegisterHandlers(event.GroupATMessageEventHandler(func(_ *dto.WSPayload,
+		data *dto.WSGroupATMessageData) error {
+		started <- struct{}{}
+		<-release
+		switch data.Content {
+		case "fail":
+			return handlerErr
+		case "panic":
+			panic("boom")
+		}
+		return nil
+	}))
+	var lock sync.Mutex
+	deadLetters := map[string]error{}
+	p := pool.New(pool.Config{Workers: 1, QueueSize: 2, Overflow: pool.OverflowReject})
+	s := NewServer(testCredentials, WithDispatcher(dispatcher),
+		WithAsyncDispatch(p, func(payload *dto.WSPayload, err error) {
+			lock.Lock()
+			defer lock.Unlock()
+			deadLetters[payload.EventID] = err
+		}))
+
+	post := func(id, content string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, newSignedRequest(t,
+			`{"op":0,"id":"`+id+`","t":"GROUP_AT_MESSAGE_CREATE","d":{"content":"`+content+`"}}`))
+		return w
+	}
+	// handler 阻塞时也能立即回包
+	for i, c := range [][]string{{"1", "ok"}, {"2", "fail"}, {"3", "panic"}} {
+		w := post(c[0], c[1])
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, GenDispatchACK(true), w.Body.String())
+		if i == 0 {
+			<-started
+		}
+	}
+	// 第一个事件正在执行，队列中有两个事件，第四个事件被拒绝，由平台重试
+	w := post("4", "ok")
+	assert.Equal(t, GenDispatchACK(false), w.Body.String())
+
+	close(release)
+	p.Close()
+	assert.Len(t, deadLetters, 2)
+	assert.True(t, errors.Is(deadLetters["2"], handlerErr))
+	assert.Contains(t, deadLetters["3"].Error(), "boom")
+}
+
+func TestServer_AsyncDispatchDropOldest(t *testing.T) {
+	dispatcher := event.NewDispatcher()
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	dispatcher.RegisterHandlers(event.GroupATMessageEventHandler(func(_ *dto.WSPayload,
+		_ *dto.WSGroupATMessageData) error {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		return nil
+	}))
+	var lock sync.Mutex
+	deadLetters := map[string]error{}
+	p := pool.New(pool.Config{Workers: 1, QueueSize: 1, Overflow: pool.OverflowDropOldest})
+	s := NewServer(testCredentials, WithDispatcher(dispatcher),
+		WithAsyncDispatch(p, func(payload *dto.WSPayload, err error) {
+			lock.Lock()
+			defer lock.Unlock()
+			deadLetters[payload.EventID] = err
+		}))
+
+	for _, id := range []string{"1", "2", "3"} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, newSignedRequest(t,
+			`{"op":0,"id":"`+id+`","t":"GROUP_AT_MESSAGE_CREATE","d":{"content":"ok"}}`))
+		assert.Equal(t, GenDispatchACK(true), w.Body.String())
+		if id == "1" {
+			<-started
+		}
+	}
+	// 第一个事件正在执行，第三个事件挤掉了队列中的第二个事件
+	close(release)
+	p.Close()
+	assert.Len(t, deadLetters, 1)
+	assert.True(t, errors.Is(deadLetters["2"], pool.ErrDropped))
+}
@@ -0,0 +1,69 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/tencent-connect/botgo/event"
+	"github.com/tencent-connect/botgo/event/pool"
+)
+
+// Option is a function that configures a Server.
+type Option func(s *Server)
+
+// WithDispatcher 指定事件投递的分发器，默认为 event.DefaultDispatcher，用于同一进程中运行多个机器人
+func WithDispatcher(dispatcher *event.Dispatcher) Option {
+	return func(s *Server) {
+		s.dispatcher = dispatcher
+	}
+}
+
+// WithMaxBodySize 指定请求 body 的最大字节数，超过时返回 413，默认为 DefaultMaxBodySize
+func WithMaxBodySize(n int64) Option {
+	return func(s *Server) {
+		s.maxBodySize = n
+	}
+}
+
+// WithAdditionalSecrets 除了 credentials 中的 secret 之外，额外接受使用这些 secret 生成的签名，用于 secret 轮换
+// 回调地址校验的回包始终使用 credentials 中的 secret 生成签名
+func WithAdditionalSecrets(secrets ...string) Option {
+	return func(s *Server) {
+		s.secrets = append(s.secrets, secrets...)
+	}
+}
+
+// WithRequestHook 添加请求处理完成之后的回调，可用于记录请求日志与监控，按照添加的顺序执行
+func WithRequestHook(hook RequestHook) Option {
+	return func(s *Server) {
+		s.hooks = append(s.hooks, hook)
+	}
+}
+
+// WithTimestampTolerance 拒绝签名时间戳与当前时间相差超过 tolerance 的请求，返回 401，用于防止请求被截获之后重放
+// 建议使用 DefaultTimestampTolerance，默认不检查
+func WithTimestampTolerance(tolerance time.Duration) Option {
+	return func(s *Server) {
+		s.tolerance = tolerance
+	}
+}
+
+// WithReplayCache 记录处理过的请求，拒绝 ttl 内重复的请求，返回 401
+// ttl 为 0 时，使用时间戳误差的两倍，未设置误差时使用 DefaultReplayTTL，ttl 需要覆盖时间戳允许的误差，重放才能被完全拒绝
+func WithReplayCache(cache ReplayCache, ttl time.Duration) Option {
+	return func(s *Server) {
+		s.replayCache = cache
+		s.replayTTL = ttl
+	}
+}
+
+// WithAsyncDispatch 校验签名并解析事件之后立即回包，事件投递到协程池 p 中在后台处理，避免 handler 耗时过长导致平台超时重试
+// handler 返回错误或者 panic 时调用 deadLetter，平台不会重试
+// 投递失败（如队列已满并且使用 pool.OverflowReject）时回包失败，由平台重试，使用 pool.OverflowDropOldest 时被丢弃的事件交给 deadLetter
+// p 由使用方负责关闭，Shutdown 之后调用 p.Close 等待排队的事件处理完成
+// 不设置时，事件在请求中同步处理，handler 返回错误时回包失败，由平台重试
+func WithAsyncDispatch(p *pool.Pool, deadLetter DeadLetterHandler) Option {
+	return func(s *Server) {
+		s.pool = p
+		s.deadLetter = deadLetter
+	}
+}
@@ -0,0 +1,103 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/tencent-connect/botgo/interaction/signature"
+)
+
+// 防重放的默认配置
+const (
+	DefaultTimestampTolerance = 5 * time.Minute  // 签名时间戳允许的误差
+	DefaultReplayTTL          = 10 * time.Minute // 请求记录的保存时间
+)
+
+var (
+	// ErrTimestampExpired 签名时间戳超出了允许的误差范围
+	ErrTimestampExpired = errors.New("signature timestamp expired")
+	// ErrReplayed 相同签名的请求已经处理过，可能是被截获之后的重放
+	ErrReplayed = errors.New("request replayed")
+)
+
+// ReplayCache 记录处理过的请求，用于拒绝重放的请求，多副本部署时需要使用共享的存储，如 NewRedisReplayCache
+type ReplayCache interface {
+	// Add 记录 key，ttl 之后过期，key 已经存在时返回 false
+	Add(ctx context.Context, key string, ttl time.Duration) (bool, error)
+}
+
+// checkReplay 检查时间戳与重放，需要在签名验证通过之后调用，签名覆盖了时间戳与 body，相同的签名即为重放的请求，
+// 平台重试时会使用新的时间戳，不受影响
+func (s *Server) checkReplay(ctx context.Context, header http.Header) error {
+	if s.tolerance > 0 {
+		ts, err := strconv.ParseInt(header.Get(signature.HeaderTimestamp), 10, 64)
+		if err != nil {
+			return ErrTimestampExpired
+		}
+		if diff := time.Since(time.Unix(ts, 0)); diff > s.tolerance || diff < -s.tolerance {
+			return ErrTimestampExpired
+		}
+	}
+	if s.replayCache == nil {
+		return nil
+	}
+	ok, err := s.replayCache.Add(ctx, header.Get(signature.HeaderSig), s.replayTTL)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrReplayed
+	}
+	return nil
+}
+
+// NewMemoryReplayCache 创建进程内的 ReplayCache，只适用于单副本部署
+func NewMemoryReplayCache() ReplayCache {
+	return &memoryReplayCache{keys: make(map[string]time.Time)}
+}
+
+type memoryReplayCache struct {
+	lock        sync.Mutex
+	keys        map[string]time.Time // key 的过期时间
+	nextCleanup time.Time
+}
+
+// Add 实现 ReplayCache
+func (c *memoryReplayCache) Add(_ context.Context, key string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if now.After(c.nextCleanup) {
+		for k, expireAt := range c.keys {
+			if now.After(expireAt) {
+				delete(c.keys, k)
+			}
+		}
+		c.nextCleanup = now.Add(ttl)
+	}
+	if expireAt, ok := c.keys[key]; ok && !now.After(expireAt) {
+		return false, nil
+	}
+	c.keys[key] = now.Add(ttl)
+	return true, nil
+}
+
+// NewRedisReplayCache 创建基于 redis 的 ReplayCache，多个副本共享，prefix 为 key 的前缀
+func NewRedisReplayCache(client redis.Cmdable, prefix string) ReplayCache {
+	return &redisReplayCache{client: client, prefix: prefix}
+}
+
+type redisReplayCache struct {
+	client redis.Cmdable
+	prefix string
+}
+
+// Add 实现 ReplayCache
+func (c *redisReplayCache) Add(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	return c.client.SetNX(ctx, c.prefix+key, 1, ttl).Result()
+}
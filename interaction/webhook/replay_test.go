@@ -0,0 +1,55 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_Replay(t *testing.T) {
+	var lastErr error
+	s := NewServer(testCredentials,
+		WithTimestampTolerance(time.Minute),
+		WithReplayCache(NewMemoryReplayCache(), 0),
+		WithRequestHook(func(info *RequestInfo) {
+			lastErr = info.Err
+		}))
+	assert.Equal(t, 2*time.Minute, s.replayTTL)
+
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, newSignedRequestAt(t, `{"op":1,"d":1}`, now))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// 完全相同的请求被重放
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, newSignedRequestAt(t, `{"op":1,"d":1}`, now))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.True(t, errors.Is(lastErr, ErrReplayed))
+
+	// 时间戳过期
+	old := strconv.FormatInt(time.Now().Add(-2*time.Minute).Unix(), 10)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, newSignedRequestAt(t, `{"op":1,"d":2}`, old))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.True(t, errors.Is(lastErr, ErrTimestampExpired))
+}
+
+func TestMemoryReplayCache(t *testing.T) {
+	c := NewMemoryReplayCache()
+	ctx := context.Background()
+	ok, err := c.Add(ctx, "a", 20*time.Millisecond)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	ok, _ = c.Add(ctx, "a", 20*time.Millisecond)
+	assert.False(t, ok)
+	time.Sleep(30 * time.Millisecond)
+	ok, _ = c.Add(ctx, "a", 20*time.Millisecond)
+	assert.True(t, ok)
+}
@@ -0,0 +1,110 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"sort"
+	"sync"
+
+	"github.com/tencent-connect/botgo/constant"
+	"github.com/tencent-connect/botgo/event"
+	"github.com/tencent-connect/botgo/log"
+	"github.com/tencent-connect/botgo/token"
+)
+
+// Router 多机器人的 http 回调路由，多个机器人共用同一个回调地址
+// 根据请求头中的 AppID 选择机器人，请求头中没有时使用路径的最后一段，如 /qqbot/{appid}
+// 使用对应机器人的 secret 验证签名，并投递到对应机器人的分发器，机器人可以在运行时注册与移除
+//
+//	r := webhook.NewRouter(webhook.WithMaxBodySize(1 << 20))
+//	r.Register(credentials, dispatcher)
+//	go r.ListenAndServe(":8080")
+type Router struct {
+	opts []Option
+
+	lock    sync.RWMutex
+	servers map[string]*Server // AppID -> Server
+
+	listener
+}
+
+// NewRouter 创建多机器人的回调路由，opts 对所有机器人生效
+func NewRouter(opts ...Option) *Router {
+	return &Router{
+		opts:    opts,
+		servers: make(map[string]*Server),
+	}
+}
+
+// Register 注册机器人，已经注册的 AppID 会被替换，可用于更新 secret，opts 在公共配置之后生效
+func (r *Router) Register(credentials *token.QQBotCredentials, dispatcher *event.Dispatcher, opts ...Option) {
+	all := make([]Option, 0, len(r.opts)+len(opts)+1)
+	all = append(all, r.opts...)
+	all = append(all, WithDispatcher(dispatcher))
+	all = append(all, opts...)
+	s := NewServer(credentials, all...)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.servers[credentials.AppID] = s
+}
+
+// Remove 移除机器人，之后该机器人的回调请求返回 404，AppID 未注册时返回 false
+func (r *Router) Remove(appID string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.servers[appID]; !ok {
+		return false
+	}
+	delete(r.servers, appID)
+	return true
+}
+
+// AppIDs 已经注册的机器人
+func (r *Router) AppIDs() []string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	appIDs := make([]string, 0, len(r.servers))
+	for appID := range r.servers {
+		appIDs = append(appIDs, appID)
+	}
+	sort.Strings(appIDs)
+	return appIDs
+}
+
+// ServeHTTP 将回调请求交给对应机器人的 Server 处理，找不到机器人时返回 404
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	appID := req.Header.Get(constant.HeaderBotAppID)
+	if appID == "" {
+		appID = path.Base(req.URL.Path)
+	}
+	r.lock.RLock()
+	s, ok := r.servers[appID]
+	r.lock.RUnlock()
+	if !ok {
+		log.Errorf("webhook bot not found, appID: %s, traceID: %s", appID, req.Header.Get(constant.HeaderTraceID))
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	s.ServeHTTP(w, req)
+}
+
+// ListenAndServe 在 addr 上监听并处理所有机器人的回调请求，调用 Shutdown 之后返回 nil
+func (r *Router) ListenAndServe(addr string) error {
+	return r.listen(addr, r, func(srv *http.Server) error {
+		return srv.ListenAndServe()
+	})
+}
+
+// ListenAndServeTLS 与 ListenAndServe 相同，使用 https
+func (r *Router) ListenAndServeTLS(addr, certFile, keyFile string) error {
+	return r.listen(addr, r, func(srv *http.Server) error {
+		return srv.ListenAndServeTLS(certFile, keyFile)
+	})
+}
+
+// Shutdown 停止监听，等待正在处理的请求完成，等待时间受 ctx 控制
+func (r *Router) Shutdown(ctx context.Context) error {
+	return r.shutdown(ctx)
+}
@@ -0,0 +1,61 @@
+package webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tencent-connect/botgo/constant"
+	"github.com/tencent-connect/botgo/dto"
+	"github.com/tencent-connect/botgo/event"
+	"github.com/tencent-connect/botgo/token"
+)
+
+func TestRouter(t *testing.T) {
+	var got []string
+	newDispatcher := func(name string) *event.Dispatcher {
+		d := event.NewDispatcher()
+		d.RegisterHandlers(event.GroupATMessageEventHandler(func(payload *dto.WSPayload,
+			_ *dto.WSGroupATMessageData) error {
+			got = append(got, name+":"+payload.Session.AppID)
+			return nil
+		}))
+		return d
+	}
+	r := NewRouter()
+	r.Register(testCredentials, newDispatcher("a"))
+	r.Register(&token.QQBotCredentials{AppID: "other", AppSecret: "other-secret"}, newDispatcher("b"))
+	assert.Equal(t, []string{"appid", "other"}, r.AppIDs())
+
+	body := `{"op":0,"t":"GROUP_AT_MESSAGE_CREATE","d":{"content":"hi"}}`
+	// 通过请求头选择机器人
+	req := newSignedRequest(t, body)
+	req.Header.Set(constant.HeaderBotAppID, "appid")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, GenDispatchACK(true), w.Body.String())
+
+	// 使用其他机器人的 secret 签名的请求不能通过验证
+	req = newSignedRequest(t, body)
+	req.Header.Set(constant.HeaderBotAppID, "other")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// 通过路径选择机器人
+	req = newSignedRequest(t, body)
+	req.URL.Path = "/qqbot/appid"
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"a:appid", "a:appid"}, got)
+
+	assert.True(t, r.Remove("appid"))
+	assert.False(t, r.Remove("appid"))
+	req = newSignedRequest(t, body)
+	req.Header.Set(constant.HeaderBotAppID, "appid")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
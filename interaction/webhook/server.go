@@ -0,0 +1,277 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tencent-connect/botgo/constant"
+	"github.com/tencent-connect/botgo/dto"
+	"github.com/tencent-connect/botgo/event"
+	"github.com/tencent-connect/botgo/event/pool"
+	"github.com/tencent-connect/botgo/interaction/signature"
+	"github.com/tencent-connect/botgo/log"
+	"github.com/tencent-connect/botgo/token"
+)
+
+// DefaultMaxBodySize 默认的请求 body 最大字节数
+const DefaultMaxBodySize = 1 << 20
+
+var (
+	// ErrBodyTooLarge 请求 body 超过了最大字节数
+	ErrBodyTooLarge = errors.New("request body too large")
+	// ErrInvalidSignature 签名验证不通过
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+// RequestInfo 一次回调请求的处理结果
+type RequestInfo struct {
+	Request   *http.Request
+	TraceID   string        // 平台的 traceID，用于问题排查
+	OPCode    dto.OPCode    // 解析 payload 之后才有值
+	EventType dto.EventType // 事件类型，只有事件包才有值
+	BodySize  int
+	Status    int           // 返回的 http 状态码
+	Latency   time.Duration // 处理耗时，包含事件 handler 的执行时间
+	Err       error         // 处理失败的原因，事件 handler 返回的错误也会记录在这里
+}
+
+// RequestHook 请求处理完成之后的回调
+type RequestHook func(info *RequestInfo)
+
+// Server http 回调服务，实现了 http.Handler，会自动进行签名验证，回调地址校验，心跳包回复，并将事件投递给分发器
+//
+//	s := webhook.NewServer(credentials, webhook.WithDispatcher(dispatcher))
+//	go s.ListenAndServe(":8080")
+//	...
+//	s.Shutdown(ctx)
+//
+// 也可以挂载到已有的 http 服务上，如 http.Handle("/qqbot", s)
+type Server struct {
+	credentials *token.QQBotCredentials
+	dispatcher  *event.Dispatcher
+	maxBodySize int64
+	hooks       []RequestHook
+	secrets     []string // 轮换期间额外接受的 secret
+	verifier    *signature.Verifier
+	verifierErr error         // secret 无效时，所有请求都无法通过签名验证
+	tolerance   time.Duration // 签名时间戳允许的误差，为 0 时不检查
+	replayCache ReplayCache
+	replayTTL   time.Duration
+	pool        *pool.Pool // 不为空时异步处理事件
+	deadLetter  DeadLetterHandler
+	legacy      bool // 兼容 HTTPHandler 的回包，出错时返回空的 200，不检查请求方法
+
+	listener
+}
+
+// NewServer 创建 http 回调服务
+func NewServer(credentials *token.QQBotCredentials, opts ...Option) *Server {
+	s := &Server{
+		credentials: credentials,
+		dispatcher:  event.DefaultDispatcher,
+		maxBodySize: DefaultMaxBodySize,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	// 密钥只在创建时生成一次
+	s.verifier, s.verifierErr = signature.NewVerifier(append([]string{credentials.AppSecret}, s.secrets...)...)
+	if s.replayTTL <= 0 {
+		s.replayTTL = DefaultReplayTTL
+		if s.tolerance > 0 {
+			s.replayTTL = 2 * s.tolerance
+		}
+	}
+	return s
+}
+
+// ServeHTTP 处理回调请求，签名验证不通过或者请求被重放返回 401，body 无法解析返回 400
+// 事件处理失败时回包中的 d 为 1，平台会重试
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	info := &RequestInfo{Request: r, TraceID: r.Header.Get(constant.HeaderTraceID)}
+	info.Status, info.Err = s.serve(w, r, info)
+	info.Latency = time.Since(start)
+	for _, hook := range s.hooks {
+		hook(info)
+	}
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request, info *RequestInfo) (int, error) {
+	defer r.Body.Close()
+	if r.Method != http.MethodPost && !s.legacy {
+		return s.replyError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	}
+	body, err := s.readBody(r.Body)
+	if errors.Is(err, ErrBodyTooLarge) {
+		return s.replyError(w, http.StatusRequestEntityTooLarge, err)
+	}
+	if err != nil {
+		log.Errorf("read http callback body error: %s, traceID: %s", err, info.TraceID)
+		return s.replyError(w, http.StatusBadRequest, err)
+	}
+	info.BodySize = len(body)
+	log.Debugf("http callback body: %s,len:%d", string(body), len(body))
+	// 签名验证
+	if pass, err := s.verify(r.Header, body); err != nil || !pass {
+		log.Errorf("signature verify failed, err: %v, traceID: %s", err, info.TraceID)
+		if err == nil {
+			err = ErrInvalidSignature
+		}
+		return s.replyError(w, http.StatusUnauthorized, err)
+	}
+	// 防重放
+	if err := s.checkReplay(r.Context(), r.Header); err != nil {
+		log.Errorf("replay check failed, err: %v, traceID: %s", err, info.TraceID)
+		status := http.StatusUnauthorized
+		if !errors.Is(err, ErrTimestampExpired) && !errors.Is(err, ErrReplayed) {
+			status = http.StatusInternalServerError // 存储异常，平台会重试
+		}
+		return s.replyError(w, status, err)
+	}
+	// 解析 payload
+	payload := &dto.WSPayload{}
+	if err := json.Unmarshal(body, payload); err != nil {
+		log.Errorf("unmarshal http callback body error: %s, traceID: %s", err, info.TraceID)
+		return s.replyError(w, http.StatusBadRequest, err)
+	}
+	info.OPCode, info.EventType = payload.OPCode, payload.Type
+	// 原始数据放入，parse 的时候需要从里面提取 d
+	payload.RawMessage = body
+	payload.Session = &dto.Session{AppID: s.credentials.AppID}
+
+	if payload.OPCode == dto.HTTPCallbackValidation {
+		return s.validate(w, r.Header, payload)
+	}
+	result, err := s.handlePayload(r.Context(), payload, info.TraceID)
+	if result != "" {
+		if _, werr := io.WriteString(w, result); werr != nil {
+			log.Errorf("write http callback response error: %s, traceID: %s", werr, info.TraceID)
+		}
+	}
+	return http.StatusOK, err
+}
+
+func (s *Server) verify(header http.Header, body []byte) (bool, error) {
+	if s.verifierErr != nil {
+		return false, s.verifierErr
+	}
+	return s.verifier.Verify(header, body)
+}
+
+// readBody 读取完整的 body，不依赖 ContentLength，兼容 chunked 编码
+func (s *Server) readBody(body io.Reader) ([]byte, error) {
+	if s.maxBodySize <= 0 {
+		return ioutil.ReadAll(body)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(body, s.maxBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > s.maxBodySize {
+		return nil, ErrBodyTooLarge
+	}
+	return data, nil
+}
+
+// validate 回调地址校验
+func (s *Server) validate(w http.ResponseWriter, header http.Header, payload *dto.WSPayload) (int, error) {
+	data, _ := payload.Data.(map[string]interface{})
+	plainToken, ptOk := data["plain_token"].(string)
+	eventTs, etOk := data["event_ts"].(string)
+	if !ptOk || !etOk {
+		return s.replyError(w, http.StatusBadRequest, fmt.Errorf("callback validation data invalid: %+v", payload.Data))
+	}
+	req := &dto.WHValidationReq{
+		PlainToken: plainToken,
+		EventTs:    eventTs,
+	}
+	if s.verifierErr != nil {
+		return s.replyError(w, http.StatusInternalServerError, s.verifierErr)
+	}
+	rsp := genValidationACK(req, header, s.verifier.Generate)
+	if rsp == nil {
+		return s.replyError(w, http.StatusInternalServerError, errors.New("generate validation ack failed"))
+	}
+	if _, err := w.Write(rsp); err != nil {
+		return http.StatusOK, err
+	}
+	return http.StatusOK, nil
+}
+
+func (s *Server) replyError(w http.ResponseWriter, status int, err error) (int, error) {
+	if s.legacy {
+		return http.StatusOK, err
+	}
+	http.Error(w, http.StatusText(status), status)
+	return status, err
+}
+
+// ListenAndServe 在 addr 上监听并处理回调请求，调用 Shutdown 之后返回 nil
+func (s *Server) ListenAndServe(addr string) error {
+	return s.listen(addr, s, func(srv *http.Server) error {
+		return srv.ListenAndServe()
+	})
+}
+
+// ListenAndServeTLS 与 ListenAndServe 相同，使用 https
+func (s *Server) ListenAndServeTLS(addr, certFile, keyFile string) error {
+	return s.listen(addr, s, func(srv *http.Server) error {
+		return srv.ListenAndServeTLS(certFile, keyFile)
+	})
+}
+
+// Shutdown 停止监听，等待正在处理的请求完成，等待时间受 ctx 控制
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.shutdown(ctx)
+}
+
+// listener 管理 ListenAndServe 创建的 http 服务，用于 Shutdown
+type listener struct {
+	lock    sync.Mutex
+	closed  bool
+	servers []*http.Server
+}
+
+func (l *listener) listen(addr string, handler http.Handler, serve func(srv *http.Server) error) error {
+	l.lock.Lock()
+	if l.closed {
+		l.lock.Unlock()
+		return http.ErrServerClosed
+	}
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	l.servers = append(l.servers, srv)
+	l.lock.Unlock()
+
+	if err := serve(srv); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (l *listener) shutdown(ctx context.Context) error {
+	l.lock.Lock()
+	l.closed = true
+	servers := l.servers
+	l.servers = nil
+	l.lock.Unlock()
+
+	var err error
+	for _, srv := range servers {
+		if e := srv.Shutdown(ctx); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
@@ -0,0 +1,155 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tencent-connect/botgo/dto"
+	"github.com/tencent-connect/botgo/event"
+	"github.com/tencent-connect/botgo/interaction/signature"
+	"github.com/tencent-connect/botgo/token"
+)
+
+var testCredentials = &token.QQBotCredentials{AppID: "appid", AppSecret: "secret"}
+
+func newSignedRequest(t *testing.T, body string) *http.Request {
+	return newSignedRequestAt(t, body, "1700000000")
+}
+
+func newSignedRequestAt(t *testing.T, body, timestamp string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/qqbot", strings.NewReader(body))
+	r.Header.Set(signature.HeaderTimestamp, timestamp)
+	sig, err := signature.Generate(testCredentials.AppSecret, r.Header, []byte(body))
+	assert.Nil(t, err)
+	r.Header.Set(signature.HeaderSig, sig)
+	return r
+}
+
+func TestServer(t *testing.T) {
+	dispatcher := event.NewDispatcher()
+	handlerErr := errors.New("handler failed")
+	dispatcher.RegisterHandlers(event.GroupATMessageEventHandler(func(_ *dto.WSPayload,
+		data *dto.WSGroupATMessageData) error {
+		if data.Content == "fail" {
+			return handlerErr
+		}
+		return nil
+	}))
+	var infos []*RequestInfo
+	s := NewServer(testCredentials, WithDispatcher(dispatcher), WithMaxBodySize(256),
+		WithRequestHook(func(info *RequestInfo) {
+			infos = append(infos, info)
+		}))
+
+	t.Run("heartbeat", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, newSignedRequest(t, `{"op":1,"d":42}`))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, GenHeartbeatACK(42), w.Body.String())
+	})
+	t.Run("dispatch", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, newSignedRequest(t, `{"op":0,"t":"GROUP_AT_MESSAGE_CREATE","d":{"content":"hi"}}`))
+		assert.Equal(t, GenDispatchACK(true), w.Body.String())
+
+		w = httptest.NewRecorder()
+		s.ServeHTTP(w, newSignedRequest(t, `{"op":0,"t":"GROUP_AT_MESSAGE_CREATE","d":{"content":"fail"}}`))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, GenDispatchACK(false), w.Body.String())
+		last := infos[len(infos)-1]
+		assert.Equal(t, dto.EventType("GROUP_AT_MESSAGE_CREATE"), last.EventType)
+		assert.True(t, errors.Is(last.Err, handlerErr))
+	})
+	t.Run("chunked body", func(t *testing.T) {
+		r := newSignedRequest(t, `{"op":1,"d":7}`)
+		r.ContentLength = -1
+		r.Body = ioutil.NopCloser(&slowReader{data: []byte(`{"op":1,"d":7}`)})
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		assert.Equal(t, GenHeartbeatACK(7), w.Body.String())
+	})
+	t.Run("status codes", func(t *testing.T) {
+		badSig := newSignedRequest(t, `{"op":1,"d":42}`)
+		badSig.Header.Set(signature.HeaderTimestamp, "1700000001")
+		cases := []struct {
+			name   string
+			req    *http.Request
+			status int
+		}{
+			{"bad signature", badSig, http.StatusUnauthorized},
+			{"bad json", newSignedRequest(t, `{"op":`), http.StatusBadRequest},
+			{"bad validation", newSignedRequest(t, `{"op":13,"d":{}}`), http.StatusBadRequest},
+			{"too large", newSignedRequest(t, strings.Repeat("a", 257)), http.StatusRequestEntityTooLarge},
+			{"method", httptest.NewRequest(http.MethodGet, "/qqbot", nil), http.StatusMethodNotAllowed},
+		}
+		for _, c := range cases {
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, c.req)
+			assert.Equal(t, c.status, w.Code, c.name)
+			assert.Equal(t, c.status, infos[len(infos)-1].Status, c.name)
+			assert.NotNil(t, infos[len(infos)-1].Err, c.name)
+		}
+	})
+}
+
+// slowReader 每次只返回一个字节，模拟 chunked 编码的 body
+type slowReader struct {
+	data []byte
+}
+
+func (r *slowReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p[:1], r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	addr := l.Addr().String()
+	_ = l.Close()
+
+	s := NewServer(testCredentials)
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServe(addr)
+	}()
+	assert.Eventually(t, func() bool {
+		resp, err := http.Post("http://"+addr, "application/json", bytes.NewReader(nil))
+		if err != nil {
+			return false
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode == http.StatusUnauthorized
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Nil(t, s.Shutdown(context.Background()))
+	assert.Nil(t, <-done)
+	assert.Equal(t, http.ErrServerClosed, s.ListenAndServe(addr))
+}
+
+func TestServer_AdditionalSecrets(t *testing.T) {
+	s := NewServer(&token.QQBotCredentials{AppID: "appid", AppSecret: "rotated"},
+		WithAdditionalSecrets(testCredentials.AppSecret))
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, newSignedRequest(t, `{"op":1,"d":1}`))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	s = NewServer(&token.QQBotCredentials{AppID: "appid"})
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, newSignedRequest(t, `{"op":1,"d":1}`))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
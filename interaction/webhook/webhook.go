@@ -2,12 +2,11 @@
 package webhook
 
 import (
+	"context"
 	"encoding/json"
-	"io"
 	"net/http"
 	"os"
 
-	"github.com/tencent-connect/botgo/constant"
 	"github.com/tencent-connect/botgo/dto"
 	"github.com/tencent-connect/botgo/event"
 	"github.com/tencent-connect/botgo/interaction/signature"
@@ -45,92 +44,70 @@ var DefaultGetSecretFunc = func() string {
 // HTTPHandler 用户处理回调时间，该函数实现的是 https://pkg.go.dev/net/http#HandleFunc 所要求的 handler
 // 会自动进行签名验证，心跳包回复，以及根据使用 event.RegisterHandlers 注册的 handler 去执行不同的 handler 来处理事件
 // 如果开发者不想在接收事件的地方处理，可以实现 DefaultHandlers.Plain 然后在内部处理相关的异步生产或者转发的逻辑
+// 每次调用都会重新生成签名密钥，建议使用 NewHTTPHandler；需要配置 body 大小，请求日志等时，请使用 Server
 func HTTPHandler(w http.ResponseWriter, r *http.Request, credentials *token.QQBotCredentials) {
-	defer r.Body.Close()
-	body := make([]byte, r.ContentLength)
-	if _, err := r.Body.Read(body); err != nil && err != io.EOF {
-		log.Errorf("read http callback body error: %s", err)
-		return
-	}
-	log.Debugf("http callback body: %s,len:%d", string(body), len(body))
-	log.Debugf("http callback header: %v", r.Header)
-	traceID := r.Header.Get(constant.HeaderTraceID)
-	// 签名验证
-	if pass, err := signature.Verify(credentials.AppSecret, r.Header, body); err != nil || !pass {
-		log.Errorf("signature verify failed, err: %v, traceID: %s", err, traceID)
-		return
-	}
-	// 解析 payload
-	payload := &dto.WSPayload{}
-	if err := json.Unmarshal(body, payload); err != nil {
-		log.Errorf("unmarshal http callback body error: %s, traceID: %s", err, traceID)
-		return
-	}
-	log.Info("payload:%+v", payload)
-	// 原始数据放入，parse 的时候需要从里面提取 d
-	payload.RawMessage = body
-	payload.Session = &dto.Session{AppID: credentials.AppID}
-	var result string
-	if payload.OPCode == dto.HTTPCallbackValidation {
-		data, ok := payload.Data.(map[string]interface{})
-		if !ok {
-			log.Errorf("callback data invalid: %+v, traceID: %s", payload.Data, traceID)
-			return
-		}
-		plainToken, ptOk := data["plain_token"].(string)
-		eventTs, etOk := data["event_ts"].(string)
-		if !ptOk || !etOk {
-			log.Errorf("callback data invalid: %+v, traceID: %s", payload.Data, traceID)
-		}
-		req := &dto.WHValidationReq{
-			PlainToken: plainToken,
-			EventTs:    eventTs,
-		}
-		validationRsp := GenValidationACK(req, r.Header, credentials.AppSecret)
-		if validationRsp != nil {
-			if _, err := w.Write(validationRsp); err != nil {
-				log.Errorf("write http callback response error: %s, traceID: %s", err, traceID)
-				return
-			}
-		}
-		return
-	}
+	HTTPHandlerWithDispatcher(w, r, credentials, event.DefaultDispatcher)
+}
 
-	result = parsePayload(payload, traceID)
-	if result != "" {
-		if _, err := w.Write([]byte(result)); err != nil {
-			log.Errorf("write http callback response error: %s, traceID: %s", err, traceID)
-			return
-		}
-	}
+// HTTPHandlerWithDispatcher 与 HTTPHandler 相同，但是事件会投递到指定的分发器，用于同一进程中运行多个机器人
+func HTTPHandlerWithDispatcher(w http.ResponseWriter, r *http.Request, credentials *token.QQBotCredentials,
+	dispatcher *event.Dispatcher) {
+	NewHTTPHandler(credentials, dispatcher)(w, r)
+}
+
+// NewHTTPHandler 创建与 HTTPHandler 行为相同的 handler，签名密钥只在创建时生成一次，如
+//
+//	http.HandleFunc("/qqbot", webhook.NewHTTPHandler(credentials, event.DefaultDispatcher))
+//
+// 与 Server 不同，签名验证失败，body 无法解析等情况下返回空的 200，不检查请求方法，也不限制 body 大小
+func NewHTTPHandler(credentials *token.QQBotCredentials, dispatcher *event.Dispatcher) http.HandlerFunc {
+	s := NewServer(credentials, WithDispatcher(dispatcher), WithMaxBodySize(0))
+	s.legacy = true
+	return s.ServeHTTP
 }
 
-func parsePayload(payload *dto.WSPayload, traceID string) string {
+// handlePayload 处理心跳包与事件包，返回回包，事件处理失败时同时返回错误
+func (s *Server) handlePayload(ctx context.Context, payload *dto.WSPayload, traceID string) (string, error) {
 	// 处理心跳包
 	if payload.OPCode == dto.WSHeartbeat {
-		return GenHeartbeatACK(uint32(payload.Data.(float64)))
+		seq, _ := payload.Data.(float64)
+		return GenHeartbeatACK(uint32(seq)), nil
 	}
 	// 处理事件
 	if payload.OPCode == dto.WSDispatchEvent {
+		// 异步处理时，投递到协程池之后立即回包
+		if s.pool != nil {
+			if err := s.submit(payload, traceID); err != nil {
+				return GenDispatchACK(false), err
+			}
+			return GenDispatchACK(true), nil
+		}
 		// 解析具体事件，并投递给业务注册的 handler
-		if err := event.ParseAndHandle(payload); err != nil {
+		if err := s.dispatcher.ParseAndHandleContext(ctx, payload); err != nil {
 			log.Errorf(
 				"parseAndHandle failed, %v, traceID:%s, payload: %v", err,
 				traceID, payload,
 			)
-			return GenDispatchACK(false)
+			return GenDispatchACK(false), err
 		}
-		return GenDispatchACK(true)
+		return GenDispatchACK(true), nil
 	}
 
-	return ""
+	return "", nil
 }
 
 // GenValidationACK 生成回调校验回包
 func GenValidationACK(req *dto.WHValidationReq, header http.Header, secret string) []byte {
+	return genValidationACK(req, header, func(h http.Header, body []byte) (string, error) {
+		return signature.Generate(secret, h, body)
+	})
+}
+
+func genValidationACK(req *dto.WHValidationReq, header http.Header,
+	generate func(header http.Header, body []byte) (string, error)) []byte {
 	h := header.Clone()
 	h.Set(signature.HeaderTimestamp, req.EventTs)
-	sig, err := signature.Generate(secret, h, []byte(req.PlainToken))
+	sig, err := generate(h, []byte(req.PlainToken))
 	if err != nil {
 		log.Errorf("generate signature failed:%+v", err)
 		return nil
@@ -1,8 +1,14 @@
 package webhook
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tencent-connect/botgo/event"
+	"github.com/tencent-connect/botgo/interaction/signature"
 )
 
 func TestGenHeartbeatACK(t *testing.T) {
@@ -22,3 +28,25 @@ func TestGenDispatchACK(t *testing.T) {
 		t.Error("GenDispatchACK error")
 	}
 }
+
+func TestNewHTTPHandler(t *testing.T) {
+	handler := NewHTTPHandler(testCredentials, event.NewDispatcher())
+
+	w := httptest.NewRecorder()
+	handler(w, newSignedRequest(t, `{"op":1,"d":42}`))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, GenHeartbeatACK(42), w.Body.String())
+
+	// 兼容之前的行为，出错时返回空的 200
+	badSig := newSignedRequest(t, `{"op":1,"d":42}`)
+	badSig.Header.Set(signature.HeaderTimestamp, "1700000001")
+	for _, r := range []*http.Request{badSig, newSignedRequest(t, `{"op":`), newSignedRequest(t, `{"op":13,"d":{}}`)} {
+		w := httptest.NewRecorder()
+		handler(w, r)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	HTTPHandler(w, newSignedRequest(t, strings.Repeat(" ", 2*DefaultMaxBodySize)+`{"op":1,"d":7}`), testCredentials)
+	assert.Equal(t, GenHeartbeatACK(7), w.Body.String())
+}
@@ -0,0 +1,41 @@
+package openapi
+
+import (
+	"context"
+
+	"github.com/tencent-connect/botgo/dto"
+	"github.com/tencent-connect/botgo/openapi/options"
+)
+
+// fakeMessageAPI 记录发送的群与单聊消息，流式消息，被动回复与回复目标的测试共用
+type fakeMessageAPI struct {
+	MessageAPI
+	sent    []dto.MessageToCreate
+	targets []string
+	err     error
+	// replyErr 被动回复（带有 msg_id 或者 event_id）时返回的错误，模拟平台拒绝回复
+	replyErr error
+}
+
+func (f *fakeMessageAPI) PostC2CMessage(_ context.Context, userID string, msg dto.APIMessage,
+	_ ...options.Option) (*dto.Message, error) {
+	return f.post("c2c:"+userID, msg)
+}
+
+func (f *fakeMessageAPI) PostGroupMessage(_ context.Context, groupID string, msg dto.APIMessage,
+	_ ...options.Option) (*dto.Message, error) {
+	return f.post("group:"+groupID, msg)
+}
+
+func (f *fakeMessageAPI) post(target string, msg dto.APIMessage) (*dto.Message, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	toCreate := msg.(dto.MessageToCreate)
+	if f.replyErr != nil && (toCreate.MsgID != "" || toCreate.EventID != "") {
+		return nil, f.replyErr
+	}
+	f.sent = append(f.sent, toCreate)
+	f.targets = append(f.targets, target)
+	return &dto.Message{ID: "stream-id"}, nil
+}
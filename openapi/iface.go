@@ -6,6 +6,7 @@ import (
 
 	"github.com/tencent-connect/botgo/dto"
 	"github.com/tencent-connect/botgo/openapi/options"
+	"github.com/tencent-connect/botgo/openapi/ratelimit"
 	"golang.org/x/oauth2"
 )
 
@@ -49,10 +50,26 @@ type Base interface {
 	// Transport 透传请求，如果 sdk 没有及时跟进新的接口的变更，可以使用该方法进行透传，openapi 实现时可以按需选择是否实现该接口
 	Transport(ctx context.Context, method, url string, body interface{}) ([]byte, error)
 
-	// TraceID 返回上一次请求的 trace id
+	// TraceID 返回上一次请求的 trace id，多个协程共用 client 时无法对应到具体的调用，请使用 WithResponseMeta
 	TraceID() string
 }
 
+// RetryConfigurer 可以配置重试策略的 openapi 实现，v1 版本实现了该接口，为了不影响已有的 OpenAPI 实现，没有放到 Base 中
+//
+//	api.(openapi.RetryConfigurer).WithRetry(openapi.DefaultRetryPolicy)
+type RetryConfigurer interface {
+	// WithRetry 设置接口调用失败时的重试策略，为 nil 时不重试，默认不重试
+	WithRetry(policy *RetryPolicy) OpenAPI
+}
+
+// RateLimitConfigurer 可以配置本地限频的 openapi 实现，v1 版本实现了该接口
+//
+//	api.(openapi.RateLimitConfigurer).WithRateLimit(ratelimit.New(ratelimit.DefaultConfig))
+type RateLimitConfigurer interface {
+	// WithRateLimit 设置本地限频器，按照路由与目标限制调用频率，为 nil 时不限频
+	WithRateLimit(limiter *ratelimit.Limiter) OpenAPI
+}
+
 // WebsocketAPI websocket 接入地址
 type WebsocketAPI interface {
 	WS(ctx context.Context, params map[string]string, body string) (*dto.WebsocketAP, error)
@@ -104,6 +121,21 @@ type MessageAPI interface {
 	RetractGroupMessage(ctx context.Context, groupID, msgID string, opt ...options.Option) error
 }
 
+// MediaAPI 富媒体上传接口，v1 版本实现了该接口，为了不影响已有的 OpenAPI 实现，没有放到 MessageAPI 中
+//
+//	file, err := api.(openapi.MediaAPI).UploadGroupMedia(ctx, groupID, media)
+type MediaAPI interface {
+	// UploadGroupMedia 上传群富媒体文件，返回的 file_info 可以用于发送 RichMediaMsg 类型的群消息
+	// 有效期内向同一个群上传相同的文件（相同的链接或者内容）会直接复用之前的结果
+	UploadGroupMedia(ctx context.Context, groupID string, media *dto.RichMediaMessage, opt ...options.Option) (
+		*dto.MediaFile, error)
+
+	// UploadC2CMedia 上传C2C富媒体文件，返回的 file_info 可以用于发送 RichMediaMsg 类型的C2C消息
+	// 有效期内向同一个用户上传相同的文件（相同的链接或者内容）会直接复用之前的结果
+	UploadC2CMedia(ctx context.Context, userID string, media *dto.RichMediaMessage, opt ...options.Option) (
+		*dto.MediaFile, error)
+}
+
 // GuildAPI guild 相关接口
 type GuildAPI interface {
 	Guild(ctx context.Context, guildID string) (*dto.Guild, error)
@@ -0,0 +1,240 @@
+package openapi
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/tencent-connect/botgo/dto"
+)
+
+// 翻页拉取时每页的大小
+const (
+	guildMembersPageSize = 400
+	roleMembersPageSize  = 400
+	meGuildsPageSize     = 100
+	reactionUsersPage    = 50
+	messagesPageSize     = 20
+)
+
+// pageIterator 通用的翻页逻辑，fetch 拉取下一页，返回本页的数量以及是否已经是最后一页，空的页不代表结束
+// 每一页都通过 client 拉取，会经过 client 配置的限频与重试，ctx 取消后停止翻页
+type pageIterator struct {
+	ctx   context.Context
+	fetch func(ctx context.Context) (size int, last bool, err error)
+	index int
+	size  int
+	last  bool
+	err   error
+}
+
+func newPageIterator(ctx context.Context, fetch func(ctx context.Context) (int, bool, error)) pageIterator {
+	return pageIterator{ctx: ctx, fetch: fetch, index: -1}
+}
+
+func (p *pageIterator) next() bool {
+	p.index++
+	for p.index >= p.size {
+		if p.last || p.err != nil {
+			return false
+		}
+		if err := p.ctx.Err(); err != nil {
+			p.err = err
+			return false
+		}
+		size, last, err := p.fetch(p.ctx)
+		if err != nil {
+			p.err = err
+			return false
+		}
+		p.index, p.size, p.last = 0, size, last
+	}
+	return true
+}
+
+// Err 翻页过程中遇到的错误，正常结束时为 nil
+func (p *pageIterator) Err() error {
+	return p.err
+}
+
+// MemberIterator 成员迭代器
+//
+//	it := openapi.AllGuildMembers(ctx, api, guildID)
+//	for it.Next() {
+//		member := it.Member()
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type MemberIterator struct {
+	pageIterator
+	members []*dto.Member
+}
+
+// Next 移动到下一个成员，没有更多成员或者出错时返回 false
+func (it *MemberIterator) Next() bool {
+	return it.next()
+}
+
+// Member 当前的成员
+func (it *MemberIterator) Member() *dto.Member {
+	return it.members[it.index]
+}
+
+// AllGuildMembers 遍历频道内的所有成员
+// 平台翻页时会重复返回上一页末尾的成员，迭代器只与上一页对比 user id 去重，内存占用与页大小相关，与成员总数无关
+func AllGuildMembers(ctx context.Context, api GuildAPI, guildID string) *MemberIterator {
+	it := &MemberIterator{}
+	pager := &dto.GuildMembersPager{After: "0", Limit: strconv.Itoa(guildMembersPageSize)}
+	var previous map[string]bool // 上一页的成员
+	it.pageIterator = newPageIterator(ctx, func(ctx context.Context) (int, bool, error) {
+		members, err := api.GuildMembers(ctx, guildID, pager)
+		if err != nil {
+			return 0, false, err
+		}
+		it.members = it.members[:0]
+		current := make(map[string]bool, len(members))
+		for _, m := range members {
+			if m.User == nil || previous[m.User.ID] || current[m.User.ID] {
+				continue
+			}
+			current[m.User.ID] = true
+			it.members = append(it.members, m)
+		}
+		previous = current
+		if len(members) > 0 && members[len(members)-1].User != nil {
+			pager.After = members[len(members)-1].User.ID
+		}
+		// 没有新的成员说明已经翻到底了
+		return len(it.members), len(it.members) == 0, nil
+	})
+	return it
+}
+
+// AllRoleMembers 遍历频道内拥有指定身份组的所有成员
+func AllRoleMembers(ctx context.Context, api GuildAPI, guildID, roleID string) *MemberIterator {
+	it := &MemberIterator{}
+	pager := &dto.GuildRoleMembersPager{StartIndex: "0", Limit: strconv.Itoa(roleMembersPageSize)}
+	it.pageIterator = newPageIterator(ctx, func(ctx context.Context) (int, bool, error) {
+		members, next, err := api.GuildRoleMembers(ctx, guildID, roleID, pager)
+		if err != nil {
+			return 0, false, err
+		}
+		it.members = members
+		last := next == "" || next == pager.StartIndex
+		pager.StartIndex = next
+		return len(members), last, nil
+	})
+	return it
+}
+
+// GuildIterator 频道迭代器
+type GuildIterator struct {
+	pageIterator
+	guilds []*dto.Guild
+}
+
+// Next 移动到下一个频道，没有更多频道或者出错时返回 false
+func (it *GuildIterator) Next() bool {
+	return it.next()
+}
+
+// Guild 当前的频道
+func (it *GuildIterator) Guild() *dto.Guild {
+	return it.guilds[it.index]
+}
+
+// AllMeGuilds 遍历机器人加入的所有频道
+func AllMeGuilds(ctx context.Context, api UserAPI) *GuildIterator {
+	it := &GuildIterator{}
+	pager := &dto.GuildPager{Limit: strconv.Itoa(meGuildsPageSize)}
+	it.pageIterator = newPageIterator(ctx, func(ctx context.Context) (int, bool, error) {
+		guilds, err := api.MeGuilds(ctx, pager)
+		if err != nil {
+			return 0, false, err
+		}
+		it.guilds = guilds
+		if len(guilds) > 0 {
+			pager.After = guilds[len(guilds)-1].ID
+		}
+		return len(guilds), len(guilds) < meGuildsPageSize, nil
+	})
+	return it
+}
+
+// UserIterator 用户迭代器
+type UserIterator struct {
+	pageIterator
+	users []*dto.User
+}
+
+// Next 移动到下一个用户，没有更多用户或者出错时返回 false
+func (it *UserIterator) Next() bool {
+	return it.next()
+}
+
+// User 当前的用户
+func (it *UserIterator) User() *dto.User {
+	return it.users[it.index]
+}
+
+// AllReactionUsers 遍历对消息发表了指定表情表态的所有用户
+func AllReactionUsers(ctx context.Context, api MessageReactionAPI,
+	channelID, messageID string, emoji dto.Emoji) *UserIterator {
+	it := &UserIterator{}
+	pager := &dto.MessageReactionPager{Limit: strconv.Itoa(reactionUsersPage)}
+	it.pageIterator = newPageIterator(ctx, func(ctx context.Context) (int, bool, error) {
+		rsp, err := api.GetMessageReactionUsers(ctx, channelID, messageID, emoji, pager)
+		if err != nil {
+			return 0, false, err
+		}
+		it.users = rsp.Users
+		pager.Cookie = rsp.Cookie
+		return len(rsp.Users), rsp.IsEnd || rsp.Cookie == "", nil
+	})
+	return it
+}
+
+// MessageIterator 消息迭代器
+type MessageIterator struct {
+	pageIterator
+	messages []*dto.Message
+}
+
+// Next 移动到下一条消息，没有更多消息或者出错时返回 false
+func (it *MessageIterator) Next() bool {
+	return it.next()
+}
+
+// Message 当前的消息
+func (it *MessageIterator) Message() *dto.Message {
+	return it.messages[it.index]
+}
+
+// AllMessagesBefore 从指定的消息开始，向前遍历子频道内的历史消息，不包括 messageID 对应的消息
+// 翻页的边界可能重复返回上一页的消息，迭代器只与上一页对比消息 id 去重，内存占用与页大小相关，与消息总数无关
+func AllMessagesBefore(ctx context.Context, api MessageAPI, channelID, messageID string) *MessageIterator {
+	it := &MessageIterator{}
+	pager := &dto.MessagesPager{Type: dto.MPTBefore, ID: messageID, Limit: strconv.Itoa(messagesPageSize)}
+	previous := map[string]bool{messageID: true} // 上一页的消息，第一页与起始的消息对比
+	it.pageIterator = newPageIterator(ctx, func(ctx context.Context) (int, bool, error) {
+		messages, err := api.Messages(ctx, channelID, pager)
+		if err != nil {
+			return 0, false, err
+		}
+		it.messages = it.messages[:0]
+		current := make(map[string]bool, len(messages))
+		for _, m := range messages {
+			if previous[m.ID] || current[m.ID] {
+				continue
+			}
+			current[m.ID] = true
+			it.messages = append(it.messages, m)
+		}
+		previous = current
+		if len(it.messages) > 0 {
+			pager.ID = it.messages[len(it.messages)-1].ID
+		}
+		return len(it.messages), len(it.messages) == 0 || len(messages) < messagesPageSize, nil
+	})
+	return it
+}
@@ -0,0 +1,163 @@
+package openapi
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tencent-connect/botgo/dto"
+	"github.com/tencent-connect/botgo/openapi/options"
+)
+
+type fakeGuildAPI struct {
+	GuildAPI
+	members []*dto.Member
+	calls   int
+}
+
+// GuildMembers 模拟平台翻页，after 之后的成员，且每页会重复返回上一页的最后一个成员
+func (f *fakeGuildAPI) GuildMembers(_ context.Context, _ string, pager *dto.GuildMembersPager) (
+	[]*dto.Member, error) {
+	f.calls++
+	start := 0
+	for i, m := range f.members {
+		if m.User.ID == pager.After {
+			start = i
+		}
+	}
+	end := start + 3
+	if end > len(f.members) {
+		end = len(f.members)
+	}
+	return f.members[start:end], nil
+}
+
+func (f *fakeGuildAPI) GuildRoleMembers(_ context.Context, _ string, _ string, pager *dto.GuildRoleMembersPager) (
+	[]*dto.Member, string, error) {
+	f.calls++
+	start, _ := strconv.Atoi(pager.StartIndex)
+	if start >= len(f.members) {
+		return nil, "", errors.New("out of range")
+	}
+	end := start + 2
+	if end >= len(f.members) {
+		return f.members[start:], "", nil
+	}
+	return f.members[start:end], strconv.Itoa(end), nil
+}
+
+func newMembers(n int) []*dto.Member {
+	members := make([]*dto.Member, 0, n)
+	for i := 1; i <= n; i++ {
+		members = append(members, &dto.Member{User: &dto.User{ID: strconv.Itoa(i)}})
+	}
+	return members
+}
+
+func collect(it *MemberIterator) []string {
+	var ids []string
+	for it.Next() {
+		ids = append(ids, it.Member().User.ID)
+	}
+	return ids
+}
+
+func TestAllGuildMembers(t *testing.T) {
+	api := &fakeGuildAPI{members: newMembers(5)}
+	it := AllGuildMembers(context.Background(), api, "guild")
+	assert.Equal(t, []string{"1", "2", "3", "4", "5"}, collect(it))
+	assert.Nil(t, it.Err())
+
+	// 多页时只需要与上一页去重
+	api = &fakeGuildAPI{members: newMembers(10)}
+	it = AllGuildMembers(context.Background(), api, "guild")
+	assert.Equal(t, []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10"}, collect(it))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	it = AllGuildMembers(ctx, api, "guild")
+	assert.False(t, it.Next())
+	assert.Equal(t, context.Canceled, it.Err())
+}
+
+func TestAllRoleMembers(t *testing.T) {
+	api := &fakeGuildAPI{members: newMembers(5)}
+	it := AllRoleMembers(context.Background(), api, "guild", "role")
+	assert.Equal(t, []string{"1", "2", "3", "4", "5"}, collect(it))
+	assert.Nil(t, it.Err())
+	assert.Equal(t, 3, api.calls)
+}
+
+type fakeReactionAPI struct {
+	MessageReactionAPI
+	pages [][]*dto.User
+}
+
+func (f *fakeReactionAPI) GetMessageReactionUsers(_ context.Context, _, _ string, _ dto.Emoji,
+	pager *dto.MessageReactionPager) (*dto.MessageReactionUsers, error) {
+	page, _ := strconv.Atoi(pager.Cookie)
+	if page >= len(f.pages) {
+		return nil, errors.New("invalid cookie")
+	}
+	return &dto.MessageReactionUsers{
+		Users:  f.pages[page],
+		Cookie: strconv.Itoa(page + 1),
+		IsEnd:  page == len(f.pages)-1,
+	}, nil
+}
+
+func TestAllReactionUsers(t *testing.T) {
+	api := &fakeReactionAPI{pages: [][]*dto.User{{{ID: "1"}, {ID: "2"}}, {}, {{ID: "3"}}}}
+	it := AllReactionUsers(context.Background(), api, "channel", "message", dto.Emoji{})
+	var ids []string
+	for it.Next() {
+		ids = append(ids, it.User().ID)
+	}
+	assert.Nil(t, it.Err())
+	// 空的页不代表结束
+	assert.Equal(t, []string{"1", "2", "3"}, ids)
+}
+
+// fakeHistoryAPI 模拟平台翻页，从 pager.ID 对应的消息开始返回，每页会重复返回上一页的最后一条消息
+type fakeHistoryAPI struct {
+	MessageAPI
+	ids []string
+}
+
+func (f *fakeHistoryAPI) Messages(_ context.Context, _ string, pager *dto.MessagesPager, _ ...options.Option) (
+	[]*dto.Message, error) {
+	start := 0
+	for i, id := range f.ids {
+		if id == pager.ID {
+			start = i
+		}
+	}
+	var messages []*dto.Message
+	for _, id := range f.ids[start:] {
+		if len(messages) == messagesPageSize {
+			break
+		}
+		messages = append(messages, &dto.Message{ID: id})
+	}
+	return messages, nil
+}
+
+func TestAllMessagesBefore(t *testing.T) {
+	api := &fakeHistoryAPI{}
+	var want []string
+	for i := 0; i < 45; i++ {
+		api.ids = append(api.ids, strconv.Itoa(i))
+		if i > 0 {
+			want = append(want, strconv.Itoa(i))
+		}
+	}
+	it := AllMessagesBefore(context.Background(), api, "channel", "0")
+	var ids []string
+	for it.Next() {
+		ids = append(ids, it.Message().ID)
+	}
+	assert.Nil(t, it.Err())
+	assert.Equal(t, want, ids)
+}
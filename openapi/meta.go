@@ -0,0 +1,42 @@
+package openapi
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// ResponseMeta 单次接口调用的响应信息，重试时记录的是最后一次请求的响应
+type ResponseMeta struct {
+	TraceID    string        // 平台返回的链路追踪ID，可用于向平台反馈问题
+	StatusCode int           // http 状态码
+	Latency    time.Duration // 最后一次请求的耗时
+	Attempts   int           // 请求的次数，包括重试
+	RateLimit  RateLimitMeta // 限频相关的响应头
+	Header     http.Header   // 完整的响应头
+}
+
+// RateLimitMeta 限频相关的响应头，响应中没有对应的头部时为零值
+type RateLimitMeta struct {
+	Limit      int           // 限频周期内允许的请求数
+	Remaining  int           // 限频周期内剩余的请求数
+	RetryAfter time.Duration // 被限频时需要等待的时间
+}
+
+type responseMetaKey struct{}
+
+// WithResponseMeta 返回携带 meta 的 context，使用该 context 调用接口之后，meta 中会记录响应信息
+// meta 只对应一次调用，不要在并发的调用之间共享
+//
+//	meta := &openapi.ResponseMeta{}
+//	_, err := api.PostMessage(openapi.WithResponseMeta(ctx, meta), channelID, msg)
+//	log.Infof("traceID: %s", meta.TraceID)
+func WithResponseMeta(ctx context.Context, meta *ResponseMeta) context.Context {
+	return context.WithValue(ctx, responseMetaKey{}, meta)
+}
+
+// ResponseMetaFromContext 获取 context 中用于记录响应信息的 meta
+func ResponseMetaFromContext(ctx context.Context) (*ResponseMeta, bool) {
+	meta, ok := ctx.Value(responseMetaKey{}).(*ResponseMeta)
+	return meta, ok && meta != nil
+}
@@ -5,6 +5,7 @@ package options
 type Options struct {
 	URL     string
 	HideTip bool // 撤回消息隐藏小灰条可选参数, true: 隐藏小灰条
+	Retry   bool // 非幂等的请求失败时也按照重试策略重试，如发消息，调用方需要自行确认重试不会带来副作用
 }
 
 // Option sets client options.
@@ -23,3 +24,10 @@ func WithHideTip() Option {
 		o.HideTip = true
 	}
 }
+
+// WithRetry 对非幂等的请求开启重试，如发消息时带上 msg_seq 可以避免重复发送
+func WithRetry() Option {
+	return func(o *Options) {
+		o.Retry = true
+	}
+}
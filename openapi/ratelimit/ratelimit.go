@@ -0,0 +1,291 @@
+// Package ratelimit 实现 openapi 客户端的本地限频，按照接口路由与目标（子频道，群，用户）划分令牌桶。
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tencent-connect/botgo/errs"
+)
+
+// DefaultKeyParams 默认用于区分限频目标的路径参数，按顺序取第一个存在的参数
+// guild_id 放在最后，只有路径中没有更具体的目标时才按频道区分，如私信 /dms/{guild_id}/messages
+var DefaultKeyParams = []string{"channel_id", "group_id", "user_id", "guild_id"}
+
+// guildKeyParams 频道管理类接口按频道限频，同一个频道中操作不同成员与身份组共用额度
+var guildKeyParams = []string{"guild_id"}
+
+// DefaultConfig 默认的限频配置，对发消息的接口，以及频道成员与身份组管理的接口限频
+// 消息发送超频时平台返回错误码 22009，同样认为被限频
+var DefaultConfig = Config{
+	ErrCodes: []int{errs.APICodeMessageFrequencyLimit},
+	Routes: map[string]Limit{
+		"POST /channels/{channel_id}/messages": {Rate: 5, Burst: 5},
+		"POST /v2/groups/{group_id}/messages":  {Rate: 5, Burst: 5},
+		"POST /v2/users/{user_id}/messages":    {Rate: 5, Burst: 5},
+		"POST /dms/{guild_id}/messages":        {Rate: 2, Burst: 2},
+
+		"DELETE /guilds/{guild_id}/members/{user_id}":                 {Rate: 2, Burst: 2, KeyParams: guildKeyParams},
+		"PATCH /guilds/{guild_id}/members/{user_id}/mute":             {Rate: 2, Burst: 2, KeyParams: guildKeyParams},
+		"PATCH /guilds/{guild_id}/mute":                               {Rate: 2, Burst: 2, KeyParams: guildKeyParams},
+		"PUT /guilds/{guild_id}/members/{user_id}/roles/{role_id}":    {Rate: 2, Burst: 2, KeyParams: guildKeyParams},
+		"DELETE /guilds/{guild_id}/members/{user_id}/roles/{role_id}": {Rate: 2, Burst: 2, KeyParams: guildKeyParams},
+		"POST /guilds/{guild_id}/roles":                               {Rate: 2, Burst: 2, KeyParams: guildKeyParams},
+		"PATCH /guilds/{guild_id}/roles/{role_id}":                    {Rate: 2, Burst: 2, KeyParams: guildKeyParams},
+		"DELETE /guilds/{guild_id}/roles/{role_id}":                   {Rate: 2, Burst: 2, KeyParams: guildKeyParams},
+	},
+}
+
+const (
+	// defaultPenalty 被服务端限频且没有返回等待时间时，暂停的时间
+	defaultPenalty = time.Second
+	// minRateFactor 被限频后速率最多降低到配置的比例
+	minRateFactor = 0.125
+	// recoverFactor 每次成功之后速率恢复的比例
+	recoverFactor = 0.1
+	// bucketIdleTime 令牌桶空闲超过该时间后被清理
+	bucketIdleTime = 10 * time.Minute
+)
+
+// Limit 令牌桶的限制
+type Limit struct {
+	Rate      float64  // 每秒生成的令牌数，小于等于 0 时不限制
+	Burst     int      // 令牌桶的容量，小于 1 时按 1 处理
+	KeyParams []string // 该路由用于区分限频目标的路径参数，为空时使用 Config.KeyParams
+}
+
+// Config 限频配置
+type Config struct {
+	Default   Limit            // 未单独配置的路由使用的限制，Rate 为 0 时不限制
+	Routes    map[string]Limit // 按路由配置的限制，key 为 "METHOD /uri/template"，如 "POST /channels/{channel_id}/messages"
+	KeyParams []string         // 用于区分限频目标的路径参数，为空时使用 DefaultKeyParams
+	ErrCodes  []int            // 表示被限频的平台错误码，http 状态码为 429 时总是认为被限频
+}
+
+// Limiter 按照路由与目标划分令牌桶的限频器
+// 调用方 context 带有 deadline 时，如果等待令牌的时间超过 deadline，立刻返回 errs.ErrRateLimited，否则排队等待
+type Limiter struct {
+	config Config
+
+	lock    sync.Mutex
+	buckets map[string]*bucket
+	cleanAt time.Time
+}
+
+// New 创建限频器
+func New(config Config) *Limiter {
+	if len(config.KeyParams) == 0 {
+		config.KeyParams = DefaultKeyParams
+	}
+	return &Limiter{
+		config:  config,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Route 获取路由，method 与 uri 模板
+func Route(method, uriTemplate string) string {
+	return fmt.Sprintf("%s %s", strings.ToUpper(method), uriTemplate)
+}
+
+// Key 获取令牌桶的 key，为路由加上目标参数的值
+func (l *Limiter) Key(route string, pathParams map[string]string) string {
+	keyParams := l.config.KeyParams
+	if limit, ok := l.config.Routes[route]; ok && len(limit.KeyParams) > 0 {
+		keyParams = limit.KeyParams
+	}
+	for _, p := range keyParams {
+		if v, ok := pathParams[p]; ok {
+			return fmt.Sprintf("%s|%s=%s", route, p, v)
+		}
+	}
+	return route
+}
+
+// IsThrottled 根据 http 状态码与平台错误码判断请求是否被服务端限频
+func (l *Limiter) IsThrottled(statusCode int, errCodes ...int) bool {
+	if statusCode == http.StatusTooManyRequests {
+		return true
+	}
+	for _, code := range errCodes {
+		for _, c := range l.config.ErrCodes {
+			if c == code {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Wait 等待获取一个令牌，路由未配置限制时直接返回
+func (l *Limiter) Wait(ctx context.Context, route, key string) error {
+	b := l.getBucket(route, key)
+	if b == nil {
+		return nil
+	}
+	maxWait := time.Duration(-1)
+	if deadline, ok := ctx.Deadline(); ok {
+		maxWait = time.Until(deadline)
+	}
+	wait, ok := b.reserve(time.Now(), maxWait)
+	if !ok {
+		return errs.ErrRateLimited
+	}
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		b.cancel()
+		return ctx.Err()
+	}
+}
+
+// Throttled 服务端返回了限频错误，暂停该令牌桶 retryAfter 时间并降低速率，retryAfter 为 0 时使用默认的暂停时间
+func (l *Limiter) Throttled(route, key string, retryAfter time.Duration) {
+	if b := l.getBucket(route, key); b != nil {
+		b.throttle(time.Now(), retryAfter)
+	}
+}
+
+// Succeeded 请求成功，逐步恢复被降低的速率
+func (l *Limiter) Succeeded(route, key string) {
+	if b := l.getBucket(route, key); b != nil {
+		b.recover()
+	}
+}
+
+func (l *Limiter) getBucket(route, key string) *bucket {
+	limit, ok := l.config.Routes[route]
+	if !ok {
+		limit = l.config.Default
+	}
+	if limit.Rate <= 0 {
+		return nil
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := time.Now()
+	l.cleanIdle(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = newBucket(limit, now)
+		l.buckets[key] = b
+	}
+	return b
+}
+
+// cleanIdle 定期清理长时间未使用的令牌桶，避免目标过多时内存持续增长
+func (l *Limiter) cleanIdle(now time.Time) {
+	if now.Before(l.cleanAt) {
+		return
+	}
+	l.cleanAt = now.Add(bucketIdleTime)
+	for key, b := range l.buckets {
+		if b.idle(now) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// bucket 令牌桶，令牌可以为负数，表示已经被预占，需要等待补充
+type bucket struct {
+	lock        sync.Mutex
+	base        Limit
+	rate        float64
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+}
+
+func newBucket(limit Limit, now time.Time) *bucket {
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	return &bucket{
+		base:   limit,
+		rate:   limit.Rate,
+		tokens: float64(limit.Burst),
+		last:   now,
+	}
+}
+
+// advance 按照经过的时间补充令牌
+func (b *bucket) advance(now time.Time) {
+	if now.After(b.last) {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if burst := float64(b.base.Burst); b.tokens > burst {
+			b.tokens = burst
+		}
+		b.last = now
+	}
+}
+
+// reserve 预占一个令牌，返回需要等待的时间，maxWait 大于等于 0 且需要等待的时间超过它时，不预占并返回 false
+func (b *bucket) reserve(now time.Time, maxWait time.Duration) (time.Duration, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.advance(now)
+	var wait time.Duration
+	if tokens := b.tokens - 1; tokens < 0 {
+		wait = time.Duration(-tokens / b.rate * float64(time.Second))
+	}
+	if pause := b.pausedUntil.Sub(now); pause > wait {
+		wait = pause
+	}
+	if maxWait >= 0 && wait > maxWait {
+		return wait, false
+	}
+	b.tokens--
+	return wait, true
+}
+
+// cancel 归还预占的令牌
+func (b *bucket) cancel() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.tokens++
+}
+
+func (b *bucket) throttle(now time.Time, retryAfter time.Duration) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if retryAfter <= 0 {
+		retryAfter = defaultPenalty
+	}
+	if until := now.Add(retryAfter); until.After(b.pausedUntil) {
+		b.pausedUntil = until
+	}
+	b.advance(now)
+	b.rate /= 2
+	if floor := b.base.Rate * minRateFactor; b.rate < floor {
+		b.rate = floor
+	}
+}
+
+func (b *bucket) recover() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.rate >= b.base.Rate {
+		return
+	}
+	b.advance(time.Now())
+	b.rate += b.base.Rate * recoverFactor
+	if b.rate > b.base.Rate {
+		b.rate = b.base.Rate
+	}
+}
+
+func (b *bucket) idle(now time.Time) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return now.Sub(b.last) > bucketIdleTime && now.After(b.pausedUntil)
+}
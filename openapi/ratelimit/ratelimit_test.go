@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tencent-connect/botgo/errs"
+)
+
+const testRoute = "POST /channels/{channel_id}/messages"
+
+func TestLimiter_Wait(t *testing.T) {
+	l := New(Config{Routes: map[string]Limit{testRoute: {Rate: 10, Burst: 2}}})
+	key := l.Key(testRoute, map[string]string{"guild_id": "1", "channel_id": "2"})
+	assert.Equal(t, testRoute+"|channel_id=2", key)
+
+	ctx := context.Background()
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, l.Wait(ctx, testRoute, key))
+	}
+	// 桶容量为 2，第三个请求需要排队等待 100ms
+	assert.True(t, time.Since(start) >= 90*time.Millisecond)
+
+	// deadline 之前拿不到令牌，立刻失败
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, errs.ErrRateLimited, l.Wait(ctx, testRoute, key))
+	// 不同目标之间互不影响
+	assert.Nil(t, l.Wait(ctx, testRoute, l.Key(testRoute, map[string]string{"channel_id": "3"})))
+	// 未配置的路由不限频
+	assert.Nil(t, l.Wait(ctx, "GET /users/@me", "GET /users/@me"))
+}
+
+func TestLimiter_Throttled(t *testing.T) {
+	l := New(Config{Routes: map[string]Limit{testRoute: {Rate: 100, Burst: 10}}, ErrCodes: []int{22009}})
+	assert.True(t, l.IsThrottled(http.StatusTooManyRequests))
+	assert.True(t, l.IsThrottled(http.StatusBadRequest, 0, 22009))
+	assert.False(t, l.IsThrottled(http.StatusBadRequest, 11244))
+
+	key := l.Key(testRoute, map[string]string{"channel_id": "2"})
+	l.Throttled(testRoute, key, time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, errs.ErrRateLimited, l.Wait(ctx, testRoute, key))
+
+	b := l.getBucket(testRoute, key)
+	assert.Equal(t, float64(50), b.rate)
+	for i := 0; i < 10; i++ {
+		l.Succeeded(testRoute, key)
+	}
+	assert.Equal(t, float64(100), b.rate)
+}
+
+func TestLimiter_Key(t *testing.T) {
+	l := New(DefaultConfig)
+	dmRoute := "POST /dms/{guild_id}/messages"
+	assert.Equal(t, dmRoute+"|guild_id=g1", l.Key(dmRoute, map[string]string{"guild_id": "g1"}))
+
+	// 成员管理按频道限频，不按成员区分
+	roleRoute := "PUT /guilds/{guild_id}/members/{user_id}/roles/{role_id}"
+	assert.Equal(t, roleRoute+"|guild_id=g1",
+		l.Key(roleRoute, map[string]string{"guild_id": "g1", "user_id": "u1", "role_id": "r1"}))
+	assert.Equal(t, "GET /users/@me", l.Key("GET /users/@me", nil))
+}
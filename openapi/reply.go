@@ -0,0 +1,257 @@
+package openapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tencent-connect/botgo/dto"
+	"github.com/tencent-connect/botgo/errs"
+)
+
+// ReplyLimit 被动回复的限制，收到消息之后的 Window 时间内，最多可以回复 MaxReplies 次
+type ReplyLimit struct {
+	Window     time.Duration
+	MaxReplies int
+}
+
+// 平台对被动回复的限制
+var (
+	GroupReplyLimit = ReplyLimit{Window: 5 * time.Minute, MaxReplies: 5}  // 群消息
+	C2CReplyLimit   = ReplyLimit{Window: 60 * time.Minute, MaxReplies: 5} // 单聊消息
+	EventReplyLimit = ReplyLimit{Window: 5 * time.Minute, MaxReplies: 5}  // 互动事件
+)
+
+// ReplyExpiredError 被动回复的窗口已经过期，或者回复次数已经用完，可以使用 errors.Is(err, errs.ErrReplyExpired) 判断
+type ReplyExpiredError struct {
+	MsgID    string    // 回复的消息 ID
+	EventID  string    // 回复的事件 ID
+	Deadline time.Time // 被动回复的截止时间
+	Replies  int       // 已经回复的次数
+}
+
+// Error 实现 error 接口
+func (e *ReplyExpiredError) Error() string {
+	return fmt.Sprintf("passive reply expired, msgID:%s, eventID:%s, deadline:%s, replies:%d",
+		e.MsgID, e.EventID, e.Deadline.Format(time.RFC3339), e.Replies)
+}
+
+// Unwrap 错误分类
+func (e *ReplyExpiredError) Unwrap() error {
+	return errs.ErrReplyExpired
+}
+
+// ReplyOption 被动回复的配置
+type ReplyOption func(r *Replier)
+
+// WithReplyLimit 修改被动回复的限制，用于平台调整了限制，而 sdk 还没有跟进的情况
+func WithReplyLimit(limit ReplyLimit) ReplyOption {
+	return func(r *Replier) {
+		r.limit = limit
+	}
+}
+
+// WithProactiveFallback 被动回复过期之后，改为发送主动消息，主动消息会占用主动消息的频率
+func WithProactiveFallback() ReplyOption {
+	return func(r *Replier) {
+		r.fallback = true
+	}
+}
+
+// Replier 被动回复，记录回复的消息 ID 或者事件 ID，自动分配递增的 msg_seq，并跟踪回复窗口与剩余的回复次数
+//
+//	r := openapi.NewGroupReplier(api, data)
+//	_, err := r.Reply(ctx, &dto.MessageToCreate{Content: "hello"})
+//	if errors.Is(err, errs.ErrReplyExpired) {
+//		...
+//	}
+//
+// 可以被多个协程并发使用
+type Replier struct {
+	post     func(ctx context.Context, msg dto.APIMessage) (*dto.Message, error)
+	msgID    string
+	eventID  string
+	received time.Time
+	limit    ReplyLimit
+	fallback bool
+
+	lock    sync.Mutex
+	seq     uint32
+	replies int
+	expired bool // 平台返回了回复过期的错误
+}
+
+// NewGroupReplier 回复群@机器人的消息
+func NewGroupReplier(api MessageAPI, data *dto.WSGroupATMessageData, opts ...ReplyOption) *Replier {
+	groupID := data.GroupID
+	return newReplier(func(ctx context.Context, msg dto.APIMessage) (*dto.Message, error) {
+		return api.PostGroupMessage(ctx, groupID, msg)
+	}, data.ID, "", timestampOrNow(string(data.Timestamp)), GroupReplyLimit, opts...)
+}
+
+// NewC2CReplier 回复单聊消息
+func NewC2CReplier(api MessageAPI, data *dto.WSC2CMessageData, opts ...ReplyOption) *Replier {
+	var userID string
+	if data.Author != nil {
+		userID = data.Author.ID
+	}
+	return newReplier(func(ctx context.Context, msg dto.APIMessage) (*dto.Message, error) {
+		return api.PostC2CMessage(ctx, userID, msg)
+	}, data.ID, "", timestampOrNow(string(data.Timestamp)), C2CReplyLimit, opts...)
+}
+
+// NewInteractionReplier 回复群或者单聊中的互动事件，频道中的互动事件返回错误
+func NewInteractionReplier(api MessageAPI, data *dto.WSInteractionData, opts ...ReplyOption) (*Replier, error) {
+	var post func(ctx context.Context, msg dto.APIMessage) (*dto.Message, error)
+	switch {
+	case data.GroupOpenID != "":
+		post = func(ctx context.Context, msg dto.APIMessage) (*dto.Message, error) {
+			return api.PostGroupMessage(ctx, data.GroupOpenID, msg)
+		}
+	case data.UserOpenID != "":
+		post = func(ctx context.Context, msg dto.APIMessage) (*dto.Message, error) {
+			return api.PostC2CMessage(ctx, data.UserOpenID, msg)
+		}
+	default:
+		return nil, fmt.Errorf("interaction %s is not in group or c2c, scene:%s", data.ID, data.Scene)
+	}
+	return newReplier(post, "", data.ID, timestampOrNow(data.Timestamp), EventReplyLimit, opts...), nil
+}
+
+func newReplier(post func(context.Context, dto.APIMessage) (*dto.Message, error),
+	msgID, eventID string, received time.Time, limit ReplyLimit, opts ...ReplyOption) *Replier {
+	r := &Replier{
+		post:     post,
+		msgID:    msgID,
+		eventID:  eventID,
+		received: received,
+		limit:    limit,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Deadline 被动回复的截止时间
+func (r *Replier) Deadline() time.Time {
+	return r.received.Add(r.limit.Window)
+}
+
+// Remaining 剩余的被动回复次数，窗口过期之后为 0
+func (r *Replier) Remaining() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.remaining()
+}
+
+// Expired 是否已经不能被动回复
+func (r *Replier) Expired() bool {
+	return r.Remaining() == 0
+}
+
+// Reply 回复消息，会填充 msg_id 或者 event_id 以及 msg_seq，msg 不会被修改
+// 被动回复过期之后，配置了 WithProactiveFallback 时发送主动消息，否则返回 *ReplyExpiredError
+func (r *Replier) Reply(ctx context.Context, msg *dto.MessageToCreate) (*dto.Message, error) {
+	toCreate := *msg
+	r.lock.Lock()
+	if r.remaining() == 0 {
+		err := r.expiredError()
+		r.lock.Unlock()
+		if !r.fallback {
+			return nil, err
+		}
+		return r.post(ctx, proactive(toCreate))
+	}
+	toCreate.MsgID, toCreate.EventID, toCreate.MsgSeq = r.msgID, r.eventID, r.allocSeq()
+	// 发送之前预占回复次数，避免并发回复时超过限制
+	r.replies++
+	r.lock.Unlock()
+
+	resp, err := r.post(ctx, toCreate)
+	if errors.Is(err, errs.ErrReplyExpired) {
+		return r.onExpired(ctx, toCreate)
+	}
+	if err != nil {
+		// 发送失败，归还预占的次数
+		r.lock.Lock()
+		r.replies--
+		r.lock.Unlock()
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Stream 以流式消息的方式回复，每个分片的 msg_seq 与 Reply 共用同一个分配器，不会因为序号重复被平台去重
+// msg 不需要填写 msg_id，event_id 与 msg_seq，流式消息不检查与计入回复次数
+func (r *Replier) Stream(ctx context.Context, msg *dto.MessageToCreate, opts ...StreamOption) *StreamWriter {
+	base := dto.MessageToCreate{}
+	if msg != nil {
+		base = *msg
+	}
+	base.MsgID, base.EventID, base.MsgSeq = r.msgID, r.eventID, 0
+	w := newStreamWriter(ctx, r.post, &base, opts...)
+	w.nextSeq = r.nextSeq
+	return w
+}
+
+// nextSeq 分配下一个 msg_seq
+func (r *Replier) nextSeq() uint32 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.allocSeq()
+}
+
+// allocSeq 需要持有锁，请求失败时可能已经发送成功，msg_seq 不复用，避免被去重
+func (r *Replier) allocSeq() uint32 {
+	r.seq++
+	return r.seq
+}
+
+// onExpired 平台返回了回复过期的错误，之后不再尝试被动回复
+func (r *Replier) onExpired(ctx context.Context, msg dto.MessageToCreate) (*dto.Message, error) {
+	r.lock.Lock()
+	r.expired = true
+	err := r.expiredError()
+	r.lock.Unlock()
+	if !r.fallback {
+		return nil, err
+	}
+	return r.post(ctx, proactive(msg))
+}
+
+func (r *Replier) remaining() int {
+	if r.expired || !time.Now().Before(r.Deadline()) || r.replies >= r.limit.MaxReplies {
+		return 0
+	}
+	return r.limit.MaxReplies - r.replies
+}
+
+func (r *Replier) expiredError() error {
+	return &ReplyExpiredError{
+		MsgID:    r.msgID,
+		EventID:  r.eventID,
+		Deadline: r.Deadline(),
+		Replies:  r.replies,
+	}
+}
+
+// proactive 去掉被动回复的字段，作为主动消息发送
+func proactive(msg dto.MessageToCreate) dto.MessageToCreate {
+	msg.MsgID, msg.EventID, msg.MsgSeq = "", "", 0
+	return msg
+}
+
+// timestampOrNow 解析事件的时间，支持 RFC3339 格式以及秒级时间戳，解析失败时使用当前时间
+func timestampOrNow(ts string) time.Time {
+	if t, err := dto.Timestamp(ts).Time(); err == nil {
+		return t
+	}
+	if sec, err := strconv.ParseInt(ts, 10, 64); err == nil && sec > 0 {
+		return time.Unix(sec, 0)
+	}
+	return time.Now()
+}
@@ -0,0 +1,143 @@
+package openapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tencent-connect/botgo/dto"
+	"github.com/tencent-connect/botgo/errs"
+)
+
+func TestReplier(t *testing.T) {
+	now := dto.Timestamp(time.Now().Format(time.RFC3339))
+	t.Run("msg seq and limit", func(t *testing.T) {
+		api := &fakeMessageAPI{}
+		data := &dto.WSGroupATMessageData{ID: "msg", GroupID: "group", Timestamp: now}
+		r := NewGroupReplier(api, data, WithReplyLimit(ReplyLimit{Window: time.Minute, MaxReplies: 2}))
+		msg := &dto.MessageToCreate{Content: "hello"}
+		for i := 0; i < 2; i++ {
+			_, err := r.Reply(context.Background(), msg)
+			assert.Nil(t, err)
+		}
+		assert.Equal(t, uint32(0), msg.MsgSeq)
+		assert.True(t, r.Expired())
+
+		_, err := r.Reply(context.Background(), msg)
+		assert.True(t, errors.Is(err, errs.ErrReplyExpired))
+		var expired *ReplyExpiredError
+		assert.True(t, errors.As(err, &expired))
+		assert.Equal(t, 2, expired.Replies)
+
+		assert.Len(t, api.sent, 2)
+		assert.Equal(t, []string{"group:group", "group:group"}, api.targets)
+		assert.Equal(t, "msg", api.sent[1].MsgID)
+		assert.Equal(t, uint32(1), api.sent[0].MsgSeq)
+		assert.Equal(t, uint32(2), api.sent[1].MsgSeq)
+	})
+	t.Run("stream shares msg seq", func(t *testing.T) {
+		api := &fakeMessageAPI{}
+		data := &dto.WSGroupATMessageData{ID: "msg", GroupID: "group", Timestamp: now}
+		r := NewGroupReplier(api, data)
+		_, err := r.Reply(context.Background(), &dto.MessageToCreate{Content: "hello"})
+		assert.Nil(t, err)
+		w := r.Stream(context.Background(), &dto.MessageToCreate{MsgSeq: 1}, WithStreamBatch(100, time.Hour))
+		_, _ = w.WriteString("a")
+		_, _ = w.WriteString("b")
+		assert.Nil(t, w.Close())
+		_, err = r.Reply(context.Background(), &dto.MessageToCreate{Content: "bye"})
+		assert.Nil(t, err)
+
+		assert.Len(t, api.sent, 4)
+		var seqs []uint32
+		for _, m := range api.sent {
+			assert.Equal(t, "msg", m.MsgID)
+			seqs = append(seqs, m.MsgSeq)
+		}
+		assert.Equal(t, []uint32{1, 2, 3, 4}, seqs)
+	})
+	t.Run("window expired fallback", func(t *testing.T) {
+		api := &fakeMessageAPI{}
+		old := dto.Timestamp(time.Now().Add(-2 * time.Hour).Format(time.RFC3339))
+		r := NewC2CReplier(api, &dto.WSC2CMessageData{ID: "msg", Author: &dto.User{ID: "user"}, Timestamp: old},
+			WithProactiveFallback())
+		assert.Equal(t, 0, r.Remaining())
+		_, err := r.Reply(context.Background(), &dto.MessageToCreate{Content: "hello"})
+		assert.Nil(t, err)
+		assert.Equal(t, "c2c:user", api.targets[0])
+		assert.Equal(t, "", api.sent[0].MsgID)
+		assert.Equal(t, uint32(0), api.sent[0].MsgSeq)
+	})
+	t.Run("rejected by platform", func(t *testing.T) {
+		// 平台返回的错误码经过 errs.NewAPIError 映射为 errs.ErrReplyExpired
+		for _, code := range []int{errs.APICodeReplyExpired, errs.APICodeReplyExhausted} {
+			body := fmt.Sprintf(`{"message":"reply rejected","code":%d,"err_code":%d,"trace_id":"t1"}`, code, code)
+			api := &fakeMessageAPI{replyErr: errs.NewAPIError(http.StatusBadRequest, []byte(body), "")}
+			ts := strconv.FormatInt(time.Now().Unix(), 10)
+			r, err := NewInteractionReplier(api, &dto.WSInteractionData{ID: "event", UserOpenID: "user", Timestamp: ts},
+				WithProactiveFallback())
+			assert.Nil(t, err)
+			assert.Equal(t, EventReplyLimit.MaxReplies, r.Remaining())
+			_, err = r.Reply(context.Background(), &dto.MessageToCreate{Content: "hello"})
+			assert.Nil(t, err)
+			assert.True(t, r.Expired())
+			// 被拒绝之后改为主动消息
+			assert.Len(t, api.sent, 1)
+			assert.Equal(t, "", api.sent[0].EventID)
+			assert.Equal(t, uint32(0), api.sent[0].MsgSeq)
+		}
+
+		// 其他错误不会认为回复已经过期
+		api := &fakeMessageAPI{replyErr: errs.NewAPIError(http.StatusBadRequest, []byte(`{"code":11255}`), "")}
+		r := NewGroupReplier(api, &dto.WSGroupATMessageData{ID: "msg", GroupID: "group", Timestamp: now})
+		_, err := r.Reply(context.Background(), &dto.MessageToCreate{Content: "hello"})
+		assert.NotNil(t, err)
+		assert.False(t, errors.Is(err, errs.ErrReplyExpired))
+		assert.False(t, r.Expired())
+	})
+	t.Run("concurrent replies", func(t *testing.T) {
+		var posted, rejected int32
+		release := make(chan struct{})
+		r := newReplier(func(context.Context, dto.APIMessage) (*dto.Message, error) {
+			atomic.AddInt32(&posted, 1)
+			<-release
+			return &dto.Message{}, nil
+		}, "msg", "", time.Now(), ReplyLimit{Window: time.Minute, MaxReplies: 5})
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := r.Reply(context.Background(), &dto.MessageToCreate{Content: "hello"}); err != nil {
+					atomic.AddInt32(&rejected, 1)
+				}
+			}()
+		}
+		// 发送中的回复同样占用次数，超过限制的回复不会发出
+		assert.Eventually(t, func() bool {
+			return atomic.LoadInt32(&rejected) == 5
+		}, time.Second, 5*time.Millisecond)
+		close(release)
+		wg.Wait()
+		assert.Equal(t, int32(5), atomic.LoadInt32(&posted))
+		assert.True(t, r.Expired())
+	})
+	t.Run("failed reply releases slot", func(t *testing.T) {
+		api := &fakeMessageAPI{err: errors.New("network error")}
+		r := NewGroupReplier(api, &dto.WSGroupATMessageData{ID: "msg", GroupID: "group", Timestamp: now})
+		_, err := r.Reply(context.Background(), &dto.MessageToCreate{Content: "hello"})
+		assert.NotNil(t, err)
+		assert.Equal(t, GroupReplyLimit.MaxReplies, r.Remaining())
+	})
+	t.Run("guild interaction", func(t *testing.T) {
+		_, err := NewInteractionReplier(&fakeMessageAPI{}, &dto.WSInteractionData{ID: "event", ChannelID: "channel"})
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,42 @@
+package openapi
+
+import (
+	"net/http"
+	"time"
+)
+
+// RetryPolicy 接口调用失败时的重试策略
+// 默认只对幂等的请求（GET，HEAD，PUT，DELETE，OPTIONS）重试，其他请求需要通过 options.WithRetry 显式开启
+type RetryPolicy struct {
+	MaxAttempts  int           // 最大尝试次数，包括第一次请求，小于等于 1 时不重试
+	WaitTime     time.Duration // 第一次重试前的等待时间，之后指数增长并带有抖动
+	MaxWaitTime  time.Duration // 最大等待时间，限频头部要求等待的时间超过它时不再重试
+	StatusCodes  []int         // 可以重试的 http 状态码
+	ErrCodes     []int         // 可以重试的平台错误码，对应响应 body 中的 code 或 err_code
+	NetworkError bool          // 网络错误，超时等没有收到响应的情况是否重试
+}
+
+// DefaultRetryPolicy 推荐的重试策略，对 429 与 5xx 的响应，以及网络错误最多尝试 3 次
+// openapi 默认不重试，需要通过 RetryConfigurer.WithRetry(DefaultRetryPolicy) 开启
+var DefaultRetryPolicy = &RetryPolicy{
+	MaxAttempts: 3,
+	WaitTime:    200 * time.Millisecond,
+	MaxWaitTime: 5 * time.Second,
+	StatusCodes: []int{
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	},
+	NetworkError: true,
+}
+
+// IsIdempotentMethod 是否是幂等的请求方法，幂等的请求默认可以重试
+func IsIdempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,305 @@
+package openapi
+
+import (
+	"context"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tencent-connect/botgo/dto"
+	"github.com/tencent-connect/botgo/errs"
+)
+
+// 流式消息默认的分片策略
+const (
+	DefaultStreamBatchSize = 50                     // 缓存的字符数达到该值时发送一个分片
+	DefaultStreamInterval  = 500 * time.Millisecond // 距离上一个分片超过该时间时，有内容就发送
+	DefaultStreamMaxChunk  = 1000                   // 单个分片的最大字符数
+)
+
+// streamFirstIndex 分片的起始序号，重新生成时同样从该序号开始，index 字段为 omitempty，不能使用 0
+const streamFirstIndex = 1
+
+// StreamOption 流式消息发送器的配置
+type StreamOption func(w *StreamWriter)
+
+// WithStreamBatch 设置分片的攒批策略，缓存的字符数达到 size，或者距离上一个分片超过 interval 时发送
+func WithStreamBatch(size int, interval time.Duration) StreamOption {
+	return func(w *StreamWriter) {
+		w.batchSize = size
+		w.interval = interval
+	}
+}
+
+// WithStreamMaxChunk 设置单个分片的最大字符数，超过的内容会拆分成多个分片
+func WithStreamMaxChunk(n int) StreamOption {
+	return func(w *StreamWriter) {
+		w.maxChunk = n
+	}
+}
+
+// StreamWriter 流式消息发送器，将持续生成的文本（比如大模型的输出）以流式消息分片的方式发送
+//
+//	w := openapi.NewC2CStream(ctx, api, userID, &dto.MessageToCreate{MsgID: msgID, MsgType: dto.MarkdownMsg})
+//	for chunk := range output {
+//		if _, err := io.WriteString(w, chunk); err != nil {
+//			...
+//		}
+//	}
+//	err := w.Close()
+//
+// 第一个分片返回的消息 ID 会作为流式 ID，之后的分片序号依次递增，Close 时发送结束状态。
+// msg 中设置了 MsgSeq 时，分片依次使用递增的 msg_seq，与其他回复共用同一个消息 ID 时，使用 Replier.Stream 统一分配。
+// ctx 取消后不再发送分片，已经发出的流式消息由平台超时结束。可以被多个协程并发使用。
+type StreamWriter struct {
+	ctx  context.Context
+	post func(ctx context.Context, msg dto.APIMessage) (*dto.Message, error)
+	base dto.MessageToCreate
+
+	batchSize int
+	interval  time.Duration
+	maxChunk  int
+
+	nextSeq func() uint32 // 分配每个分片的 msg_seq，为空时不设置
+
+	lock     sync.Mutex
+	buf      []byte
+	id       string
+	index    int32
+	reset    bool
+	lastSend time.Time
+	closed   bool
+	err      error
+}
+
+// NewC2CStream 创建发送 C2C 流式消息的发送器，msg 为每个分片的公共字段，
+// MsgType 为 MarkdownMsg 时内容写入 Markdown.Content，否则写入 Content，ActionButton 与 Keyboard 只在最后一个分片发送
+func NewC2CStream(ctx context.Context, api MessageAPI, userID string, msg *dto.MessageToCreate,
+	opts ...StreamOption) *StreamWriter {
+	return newStreamWriter(ctx, func(ctx context.Context, m dto.APIMessage) (*dto.Message, error) {
+		return api.PostC2CMessage(ctx, userID, m)
+	}, msg, opts...)
+}
+
+// NewGroupStream 创建发送群流式消息的发送器，参数与 NewC2CStream 相同
+func NewGroupStream(ctx context.Context, api MessageAPI, groupID string, msg *dto.MessageToCreate,
+	opts ...StreamOption) *StreamWriter {
+	return newStreamWriter(ctx, func(ctx context.Context, m dto.APIMessage) (*dto.Message, error) {
+		return api.PostGroupMessage(ctx, groupID, m)
+	}, msg, opts...)
+}
+
+func newStreamWriter(ctx context.Context, post func(context.Context, dto.APIMessage) (*dto.Message, error),
+	msg *dto.MessageToCreate, opts ...StreamOption) *StreamWriter {
+	w := &StreamWriter{
+		ctx:       ctx,
+		post:      post,
+		batchSize: DefaultStreamBatchSize,
+		interval:  DefaultStreamInterval,
+		maxChunk:  DefaultStreamMaxChunk,
+		index:     streamFirstIndex,
+	}
+	if msg != nil {
+		w.base = *msg
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	if w.maxChunk <= 0 {
+		w.maxChunk = DefaultStreamMaxChunk
+	}
+	// 同一个 msg_id 的回复按照 msg_seq 去重，每个分片需要不同的序号，在调用方指定的序号上递增
+	if seq := w.base.MsgSeq; w.nextSeq == nil && seq > 0 {
+		w.nextSeq = func() uint32 {
+			seq++
+			return seq - 1
+		}
+	}
+	return w
+}
+
+// ID 流式消息 ID，第一个分片发送成功之前为空
+func (w *StreamWriter) ID() string {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.id
+}
+
+// Write 写入一段文本，满足攒批条件时发送分片，不完整的 utf8 字符会保留到下一次写入
+func (w *StreamWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if err := w.check(); err != nil {
+		return 0, err
+	}
+	w.buf = append(w.buf, p...)
+	if err := w.flush(false); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// WriteString 写入一段文本，同 Write
+func (w *StreamWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// WriteChan 持续读取 ch 中的文本并写入，ch 关闭后发送结束状态，ctx 取消时返回 ctx 的错误
+func (w *StreamWriter) WriteChan(ch <-chan string) error {
+	for {
+		select {
+		case s, ok := <-ch:
+			if !ok {
+				return w.Close()
+			}
+			if _, err := w.WriteString(s); err != nil {
+				return err
+			}
+		case <-w.ctx.Done():
+			return w.ctx.Err()
+		}
+	}
+}
+
+// Flush 立即发送缓存中的内容
+func (w *StreamWriter) Flush() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if err := w.check(); err != nil {
+		return err
+	}
+	return w.flush(true)
+}
+
+// Reset 丢弃缓存中还没有发送的内容，重新生成流式消息，下一个分片会带上 reset 标记，序号重新从第一个开始
+func (w *StreamWriter) Reset() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if err := w.check(); err != nil {
+		return err
+	}
+	w.buf = w.buf[:0]
+	if w.id != "" {
+		w.index, w.reset = streamFirstIndex, true
+	}
+	return nil
+}
+
+// Close 发送剩余的内容以及结束状态，重复调用返回 nil，没有发送过任何内容时不会发送消息
+func (w *StreamWriter) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.closed {
+		return w.err
+	}
+	if err := w.check(); err != nil {
+		w.closed = true
+		return err
+	}
+	w.closed = true
+	if w.id == "" && len(w.buf) == 0 {
+		return nil
+	}
+	for {
+		n := cutRunes(w.buf, w.maxChunk)
+		if n == len(w.buf) {
+			break
+		}
+		if err := w.send(n, dto.StreamStateGenerating); err != nil {
+			return err
+		}
+	}
+	return w.send(len(w.buf), dto.StreamStateDone)
+}
+
+func (w *StreamWriter) check() error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.closed {
+		return errs.ErrStreamClosed
+	}
+	if err := w.ctx.Err(); err != nil {
+		w.err = err
+		return err
+	}
+	return nil
+}
+
+// flush 发送缓存中完整的内容，force 为 false 时只在满足攒批条件时发送
+func (w *StreamWriter) flush(force bool) error {
+	for utf8.RuneCount(w.buf) > w.maxChunk {
+		if err := w.send(cutRunes(w.buf, w.maxChunk), dto.StreamStateGenerating); err != nil {
+			return err
+		}
+	}
+	n := completeRunes(w.buf)
+	if n == 0 {
+		return nil
+	}
+	if force || utf8.RuneCount(w.buf[:n]) >= w.batchSize || time.Since(w.lastSend) >= w.interval {
+		return w.send(n, dto.StreamStateGenerating)
+	}
+	return nil
+}
+
+// send 将缓存的前 n 个字节作为一个分片发送
+func (w *StreamWriter) send(n int, state int32) error {
+	msg := w.base
+	content := string(w.buf[:n])
+	if msg.MsgType == dto.MarkdownMsg {
+		md := dto.Markdown{}
+		if w.base.Markdown != nil {
+			md = *w.base.Markdown
+		}
+		md.Content = content
+		msg.Markdown = &md
+	} else {
+		msg.Content = content
+	}
+	if state != dto.StreamStateDone {
+		msg.ActionButton = nil
+		msg.Keyboard = nil
+	}
+	if w.nextSeq != nil {
+		msg.MsgSeq = w.nextSeq()
+	}
+	msg.Stream = &dto.Stream{State: state, ID: w.id, Index: w.index, Reset: w.reset}
+
+	resp, err := w.post(w.ctx, msg)
+	if err != nil {
+		w.err = err
+		return err
+	}
+	if w.id == "" && resp != nil {
+		w.id = resp.ID
+	}
+	w.buf = w.buf[:copy(w.buf, w.buf[n:])]
+	w.index++
+	w.reset = false
+	w.lastSend = time.Now()
+	return nil
+}
+
+// cutRunes 前 n 个字符的字节数
+func cutRunes(b []byte, n int) int {
+	i := 0
+	for ; n > 0 && i < len(b); n-- {
+		_, size := utf8.DecodeRune(b[i:])
+		i += size
+	}
+	return i
+}
+
+// completeRunes 去掉末尾不完整的 utf8 字符后的字节数
+func completeRunes(b []byte) int {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if utf8.FullRune(b[i:]) {
+				return len(b)
+			}
+			return i
+		}
+	}
+	return len(b)
+}
@@ -0,0 +1,98 @@
+package openapi
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tencent-connect/botgo/dto"
+	"github.com/tencent-connect/botgo/errs"
+)
+
+func TestStreamWriter(t *testing.T) {
+	t.Run("batch and done", func(t *testing.T) {
+		api := &fakeMessageAPI{}
+		w := NewC2CStream(context.Background(), api, "user", &dto.MessageToCreate{
+			MsgID:        "msg",
+			MsgSeq:       1,
+			ActionButton: &dto.ActionButton{StopGenerate: true},
+		}, WithStreamBatch(4, time.Hour))
+		for _, s := range []string{"ab", "cd", "ef", "g"} {
+			_, err := w.WriteString(s)
+			assert.Nil(t, err)
+		}
+		assert.Nil(t, w.Close())
+		assert.Nil(t, w.Close())
+		_, err := w.WriteString("f")
+		assert.True(t, errors.Is(err, errs.ErrStreamClosed))
+
+		// 第一次写入距离上一个分片超过 interval，立即发送
+		assert.Len(t, api.sent, 3)
+		assert.Equal(t, "ab", api.sent[0].Content)
+		assert.Equal(t, &dto.Stream{State: dto.StreamStateGenerating, Index: 1}, api.sent[0].Stream)
+		assert.Nil(t, api.sent[0].ActionButton)
+		assert.Equal(t, "cdef", api.sent[1].Content)
+		assert.Equal(t, &dto.Stream{State: dto.StreamStateGenerating, ID: "stream-id", Index: 2}, api.sent[1].Stream)
+		assert.Equal(t, "g", api.sent[2].Content)
+		assert.Equal(t, &dto.Stream{State: dto.StreamStateDone, ID: "stream-id", Index: 3}, api.sent[2].Stream)
+		assert.NotNil(t, api.sent[2].ActionButton)
+		assert.Equal(t, []uint32{1, 2, 3}, []uint32{api.sent[0].MsgSeq, api.sent[1].MsgSeq, api.sent[2].MsgSeq})
+	})
+	t.Run("max chunk and utf8", func(t *testing.T) {
+		api := &fakeMessageAPI{}
+		w := NewC2CStream(context.Background(), api, "user", &dto.MessageToCreate{MsgType: dto.MarkdownMsg},
+			WithStreamBatch(100, time.Hour), WithStreamMaxChunk(3))
+		text := "你好世界啊"
+		_, err := w.Write([]byte(text)[:4])
+		assert.Nil(t, err)
+		_, err = w.Write([]byte(text)[4:])
+		assert.Nil(t, err)
+		assert.Nil(t, w.Close())
+		// 第一个分片不包含被截断的字符，之后按照最大字符数拆分
+		assert.Len(t, api.sent, 3)
+		assert.Equal(t, "你", api.sent[0].Markdown.Content)
+		assert.Equal(t, "好世界", api.sent[1].Markdown.Content)
+		assert.Equal(t, "啊", api.sent[2].Markdown.Content)
+		assert.Equal(t, "", api.sent[2].Content)
+	})
+	t.Run("reset", func(t *testing.T) {
+		api := &fakeMessageAPI{}
+		w := NewC2CStream(context.Background(), api, "user", nil, WithStreamBatch(100, time.Hour))
+		_, _ = w.WriteString("first")
+		assert.Nil(t, w.Flush())
+		_, _ = w.WriteString("dropped")
+		assert.Nil(t, w.Reset())
+		_, _ = w.WriteString("second")
+		assert.Nil(t, w.Flush())
+		_, _ = w.WriteString("third")
+		assert.Nil(t, w.Close())
+		assert.Len(t, api.sent, 3)
+		assert.Equal(t, &dto.Stream{State: dto.StreamStateGenerating, Index: 1}, api.sent[0].Stream)
+		// 重新生成之后序号与第一次生成相同，从第一个开始
+		assert.Equal(t, "second", api.sent[1].Content)
+		assert.Equal(t, &dto.Stream{State: dto.StreamStateGenerating, ID: "stream-id", Index: 1, Reset: true},
+			api.sent[1].Stream)
+		assert.Equal(t, &dto.Stream{State: dto.StreamStateDone, ID: "stream-id", Index: 2}, api.sent[2].Stream)
+	})
+	t.Run("chan and cancel", func(t *testing.T) {
+		api := &fakeMessageAPI{}
+		ctx, cancel := context.WithCancel(context.Background())
+		w := NewC2CStream(ctx, api, "user", nil, WithStreamBatch(100, time.Hour))
+		ch := make(chan string, 1)
+		ch <- "hello"
+		cancel()
+		err := w.WriteChan(ch)
+		assert.True(t, errors.Is(err, context.Canceled))
+		assert.True(t, errors.Is(w.Close(), context.Canceled))
+	})
+	t.Run("send error", func(t *testing.T) {
+		api := &fakeMessageAPI{err: errors.New("boom")}
+		w := NewC2CStream(context.Background(), api, "user", nil)
+		_, err := w.WriteString("hello")
+		assert.EqualError(t, err, "boom")
+		_, err = w.WriteString("again")
+		assert.EqualError(t, err, "boom")
+	})
+}
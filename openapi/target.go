@@ -0,0 +1,197 @@
+package openapi
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tencent-connect/botgo/dto"
+	"github.com/tencent-connect/botgo/dto/keyboard"
+)
+
+// Scene 消息场景
+type Scene int
+
+// 消息场景
+const (
+	SceneGuild  Scene = iota + 1 // 频道
+	SceneDirect                  // 频道私信
+	SceneGroup                   // 群
+	SceneC2C                     // 单聊
+)
+
+// String 场景名称
+func (s Scene) String() string {
+	switch s {
+	case SceneGuild:
+		return "guild"
+	case SceneDirect:
+		return "direct"
+	case SceneGroup:
+		return "group"
+	case SceneC2C:
+		return "c2c"
+	}
+	return fmt.Sprintf("Scene(%d)", int(s))
+}
+
+// ReplyAPI 回复消息需要用到的接口，OpenAPI 实现了该接口
+type ReplyAPI interface {
+	MessageAPI
+	DirectMessageAPI
+}
+
+// ReplyTarget 回复的目标，屏蔽频道，私信，群与单聊之间的差异，按照场景调用对应的接口并组装消息
+//
+//	target, err := openapi.NewReplyTarget(api, data)
+//	if err != nil {
+//		...
+//	}
+//	_, err = target.Text(ctx, "hello")
+//
+// 群与单聊通过 Replier 回复，会自动分配 msg_seq，并在被动回复过期时返回 *ReplyExpiredError
+type ReplyTarget struct {
+	api       ReplyAPI
+	scene     Scene
+	guildID   string // 频道 ID，私信场景下为私信频道的 ID
+	channelID string
+	groupID   string
+	userID    string
+	msgID     string
+	eventID   string
+	replier   *Replier // 群与单聊场景使用
+}
+
+// NewReplyTarget 根据收到的事件创建回复的目标，支持以下事件：
+// *dto.WSMessageData，*dto.WSATMessageData，*dto.WSDirectMessageData，*dto.WSGroupATMessageData，
+// *dto.WSC2CMessageData，*dto.WSInteractionData，*dto.Interaction
+func NewReplyTarget(api ReplyAPI, data interface{}, opts ...ReplyOption) (*ReplyTarget, error) {
+	t := &ReplyTarget{api: api}
+	switch d := data.(type) {
+	case *dto.WSMessageData:
+		t.scene, t.guildID, t.channelID, t.msgID = SceneGuild, d.GuildID, d.ChannelID, d.ID
+	case *dto.WSATMessageData:
+		t.scene, t.guildID, t.channelID, t.msgID = SceneGuild, d.GuildID, d.ChannelID, d.ID
+	case *dto.WSDirectMessageData:
+		t.scene, t.guildID, t.channelID, t.msgID = SceneDirect, d.GuildID, d.ChannelID, d.ID
+	case *dto.WSGroupATMessageData:
+		t.scene, t.groupID, t.msgID = SceneGroup, d.GroupID, d.ID
+		t.replier = NewGroupReplier(api, d, opts...)
+	case *dto.WSC2CMessageData:
+		t.scene, t.msgID = SceneC2C, d.ID
+		if d.Author != nil {
+			t.userID = d.Author.ID
+		}
+		t.replier = NewC2CReplier(api, d, opts...)
+	case *dto.WSInteractionData:
+		return newInteractionTarget(t, d, opts...)
+	case *dto.Interaction:
+		return newInteractionTarget(t, (*dto.WSInteractionData)(d), opts...)
+	default:
+		return nil, fmt.Errorf("unsupported reply target data type %T", data)
+	}
+	return t, nil
+}
+
+func newInteractionTarget(t *ReplyTarget, d *dto.WSInteractionData, opts ...ReplyOption) (*ReplyTarget, error) {
+	t.eventID = d.ID
+	switch {
+	case d.GroupOpenID != "":
+		t.scene, t.groupID = SceneGroup, d.GroupOpenID
+	case d.UserOpenID != "":
+		t.scene, t.userID = SceneC2C, d.UserOpenID
+	case d.ChannelID != "":
+		t.scene, t.guildID, t.channelID = SceneGuild, d.GuildID, d.ChannelID
+		return t, nil
+	default:
+		return nil, fmt.Errorf("interaction %s has no reply target, scene:%s", d.ID, d.Scene)
+	}
+	replier, err := NewInteractionReplier(t.api, d, opts...)
+	if err != nil {
+		return nil, err
+	}
+	t.replier = replier
+	return t, nil
+}
+
+// Scene 回复的场景
+func (t *ReplyTarget) Scene() Scene {
+	return t.scene
+}
+
+// Replier 群与单聊场景下的被动回复状态，其他场景返回 nil
+func (t *ReplyTarget) Replier() *Replier {
+	return t.replier
+}
+
+// Send 发送消息，会填充被动回复需要的字段，msg 不会被修改
+func (t *ReplyTarget) Send(ctx context.Context, msg *dto.MessageToCreate) (*dto.Message, error) {
+	switch t.scene {
+	case SceneGroup, SceneC2C:
+		return t.replier.Reply(ctx, msg)
+	case SceneDirect:
+		return t.api.PostDirectMessage(ctx, &dto.DirectMessage{GuildID: t.guildID, ChannelID: t.channelID},
+			t.reference(msg))
+	default:
+		return t.api.PostMessage(ctx, t.channelID, t.reference(msg))
+	}
+}
+
+// Text 回复文本消息
+func (t *ReplyTarget) Text(ctx context.Context, content string) (*dto.Message, error) {
+	return t.Send(ctx, &dto.MessageToCreate{Content: content, MsgType: dto.TextMsg})
+}
+
+// Markdown 回复 markdown 消息
+func (t *ReplyTarget) Markdown(ctx context.Context, md *dto.Markdown) (*dto.Message, error) {
+	return t.Keyboard(ctx, md, nil)
+}
+
+// Keyboard 回复带按钮的 markdown 消息，按钮需要与 markdown 一起发送
+func (t *ReplyTarget) Keyboard(ctx context.Context, md *dto.Markdown,
+	kb *keyboard.MessageKeyboard) (*dto.Message, error) {
+	return t.Send(ctx, &dto.MessageToCreate{MsgType: dto.MarkdownMsg, Markdown: md, Keyboard: kb})
+}
+
+// Image 回复图片消息，群与单聊场景会先上传图片
+func (t *ReplyTarget) Image(ctx context.Context, url string) (*dto.Message, error) {
+	return t.Media(ctx, dto.NewRichMediaFromURL(dto.RichMediaImage, url))
+}
+
+// Media 回复富媒体消息，群与单聊场景会先上传文件，api 需要实现 MediaAPI，频道与私信场景只支持图片链接
+func (t *ReplyTarget) Media(ctx context.Context, media *dto.RichMediaMessage) (*dto.Message, error) {
+	switch t.scene {
+	case SceneGroup, SceneC2C:
+		file, err := t.upload(ctx, media)
+		if err != nil {
+			return nil, err
+		}
+		return t.Send(ctx, &dto.MessageToCreate{Content: media.Content, MsgType: dto.RichMediaMsg,
+			Media: file.MediaInfo()})
+	default:
+		if media.FileType != dto.RichMediaImage || media.URL == "" {
+			return nil, fmt.Errorf("scene %s only supports image url, file type:%d", t.scene, media.FileType)
+		}
+		return t.Send(ctx, &dto.MessageToCreate{Content: media.Content, Image: media.URL})
+	}
+}
+
+func (t *ReplyTarget) upload(ctx context.Context, media *dto.RichMediaMessage) (*dto.MediaFile, error) {
+	// 只上传文件，发送由 Send 完成，以便使用被动回复
+	uploader, ok := t.api.(MediaAPI)
+	if !ok {
+		return nil, fmt.Errorf("api %T does not implement MediaAPI", t.api)
+	}
+	upload := *media
+	upload.SrvSendMsg = false
+	if t.scene == SceneGroup {
+		return uploader.UploadGroupMedia(ctx, t.groupID, &upload)
+	}
+	return uploader.UploadC2CMedia(ctx, t.userID, &upload)
+}
+
+// reference 频道与私信场景填充被动回复的字段
+func (t *ReplyTarget) reference(msg *dto.MessageToCreate) *dto.MessageToCreate {
+	toCreate := *msg
+	toCreate.MsgID, toCreate.EventID = t.msgID, t.eventID
+	return &toCreate
+}
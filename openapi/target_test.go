@@ -0,0 +1,88 @@
+package openapi
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tencent-connect/botgo/dto"
+	"github.com/tencent-connect/botgo/openapi/options"
+)
+
+type fakeReplyAPI struct {
+	fakeMessageAPI
+	DirectMessageAPI
+	uploads []string
+}
+
+func (f *fakeReplyAPI) PostMessage(_ context.Context, channelID string, msg *dto.MessageToCreate,
+	_ ...options.Option) (*dto.Message, error) {
+	return f.post("guild:"+channelID, *msg)
+}
+
+func (f *fakeReplyAPI) PostDirectMessage(_ context.Context, dm *dto.DirectMessage, msg *dto.MessageToCreate,
+	_ ...options.Option) (*dto.Message, error) {
+	return f.post("direct:"+dm.GuildID, *msg)
+}
+
+func (f *fakeReplyAPI) UploadGroupMedia(_ context.Context, groupID string, media *dto.RichMediaMessage,
+	_ ...options.Option) (*dto.MediaFile, error) {
+	f.uploads = append(f.uploads, "group:"+groupID+":"+media.URL)
+	return &dto.MediaFile{FileInfo: []byte("info")}, nil
+}
+
+func (f *fakeReplyAPI) UploadC2CMedia(_ context.Context, userID string, media *dto.RichMediaMessage,
+	_ ...options.Option) (*dto.MediaFile, error) {
+	f.uploads = append(f.uploads, "c2c:"+userID+":"+media.URL)
+	return &dto.MediaFile{FileInfo: []byte("info")}, nil
+}
+
+func TestReplyTarget(t *testing.T) {
+	now := dto.Timestamp(time.Now().Format(time.RFC3339))
+	t.Run("route by scene", func(t *testing.T) {
+		api := &fakeReplyAPI{}
+		events := []interface{}{
+			&dto.WSATMessageData{ID: "m1", GuildID: "g", ChannelID: "c"},
+			&dto.WSDirectMessageData{ID: "m2", GuildID: "dm", ChannelID: "c"},
+			&dto.WSGroupATMessageData{ID: "m3", GroupID: "group", Timestamp: now},
+			&dto.WSC2CMessageData{ID: "m4", Author: &dto.User{ID: "user"}, Timestamp: now},
+			&dto.WSInteractionData{ID: "e1", GroupOpenID: "group", Timestamp: string(now)},
+		}
+		scenes := []Scene{SceneGuild, SceneDirect, SceneGroup, SceneC2C, SceneGroup}
+		for i, data := range events {
+			target, err := NewReplyTarget(api, data)
+			assert.Nil(t, err)
+			assert.Equal(t, scenes[i], target.Scene())
+			_, err = target.Text(context.Background(), "hello")
+			assert.Nil(t, err)
+		}
+		assert.Equal(t, []string{"guild:c", "direct:dm", "group:group", "c2c:user", "group:group"}, api.targets)
+		assert.Equal(t, "m1", api.sent[0].MsgID)
+		assert.Equal(t, uint32(0), api.sent[0].MsgSeq)
+		assert.Equal(t, "m3", api.sent[2].MsgID)
+		assert.Equal(t, uint32(1), api.sent[2].MsgSeq)
+		assert.Equal(t, "e1", api.sent[4].EventID)
+		assert.Equal(t, "", api.sent[4].MsgID)
+	})
+	t.Run("image", func(t *testing.T) {
+		api := &fakeReplyAPI{}
+		group, _ := NewReplyTarget(api, &dto.WSGroupATMessageData{ID: "m", GroupID: "group", Timestamp: now})
+		_, err := group.Image(context.Background(), "https://a/b.png")
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"group:group:https://a/b.png"}, api.uploads)
+		assert.Equal(t, dto.RichMediaMsg, api.sent[0].MsgType)
+		assert.Equal(t, []byte("info"), api.sent[0].Media.FileInfo)
+
+		guild, _ := NewReplyTarget(api, &dto.WSMessageData{ID: "m", ChannelID: "c"})
+		_, err = guild.Image(context.Background(), "https://a/b.png")
+		assert.Nil(t, err)
+		assert.Equal(t, "https://a/b.png", api.sent[1].Image)
+		_, err = guild.Media(context.Background(), dto.NewRichMediaFromURL(dto.RichMediaVideo, "https://a/b.mp4"))
+		assert.NotNil(t, err)
+	})
+	t.Run("unsupported", func(t *testing.T) {
+		_, err := NewReplyTarget(&fakeReplyAPI{}, &dto.Message{})
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,129 @@
+package v1
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tencent-connect/botgo/dto"
+	"github.com/tencent-connect/botgo/openapi/options"
+)
+
+const (
+	mediaSceneGroup = "group"
+	mediaSceneC2C   = "c2c"
+	// mediaTTLMargin 提前让缓存失效的时间，避免发送消息的时候 file_info 刚好过期
+	mediaTTLMargin = time.Minute
+)
+
+// UploadGroupMedia 上传群富媒体文件
+func (o *openAPI) UploadGroupMedia(ctx context.Context, groupID string, media *dto.RichMediaMessage,
+	opt ...options.Option) (*dto.MediaFile, error) {
+	return o.uploadMedia(ctx, mediaSceneGroup, "group_id", groupID, groupRichMediaURI, media, opt...)
+}
+
+// UploadC2CMedia 上传C2C富媒体文件
+func (o *openAPI) UploadC2CMedia(ctx context.Context, userID string, media *dto.RichMediaMessage,
+	opt ...options.Option) (*dto.MediaFile, error) {
+	return o.uploadMedia(ctx, mediaSceneC2C, "user_id", userID, c2cRichMediaURI, media, opt...)
+}
+
+func (o *openAPI) uploadMedia(ctx context.Context, scene, param, target string, u uri,
+	media *dto.RichMediaMessage, opt ...options.Option) (*dto.MediaFile, error) {
+	// 直接发送的富媒体消息会占用消息频率，不能复用
+	cacheable := !media.SrvSendMsg
+	key := mediaCacheKey(scene, target, media)
+	if cacheable {
+		if file, ok := o.mediaCache.get(key); ok {
+			return file, nil
+		}
+	}
+	reqCMD := o.request(ctx).
+		SetResult(dto.MediaFile{}).
+		SetPathParam(param, target).
+		SetBody(media)
+	resp, err := baseRequest(ctx, reqCMD, http.MethodPost, o.getURL(u), opt...)
+	if err != nil {
+		return nil, err
+	}
+	file := resp.Result().(*dto.MediaFile)
+	if cacheable {
+		o.mediaCache.set(key, file)
+	}
+	return file, nil
+}
+
+// mediaCacheKey 上传结果只能发送给上传时的目标，按照场景，目标（群或者用户的 openid），文件类型与文件内容区分
+func mediaCacheKey(scene, target string, media *dto.RichMediaMessage) string {
+	content := media.URL
+	if media.FileData != "" {
+		sum := sha256.Sum256([]byte(media.FileData))
+		content = hex.EncodeToString(sum[:])
+	}
+	return fmt.Sprintf("%s|%s|%d|%s", scene, target, media.FileType, content)
+}
+
+// mediaCache 缓存有效期内的富媒体上传结果，按照平台返回的 ttl 提前过期，ttl 为 0 时不缓存
+// 写入与读取时都会复制，调用方修改返回的结果不会影响缓存
+type mediaCache struct {
+	lock  sync.Mutex
+	items map[string]mediaCacheItem
+}
+
+type mediaCacheItem struct {
+	file     *dto.MediaFile
+	expireAt time.Time
+}
+
+func (c *mediaCache) get(key string) (*dto.MediaFile, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	item, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	now := time.Now()
+	if now.After(item.expireAt) {
+		delete(c.items, key)
+		return nil, false
+	}
+	file := copyMediaFile(item.file)
+	// 返回剩余的有效期
+	file.TTL = uint(item.expireAt.Sub(now) / time.Second)
+	return file, true
+}
+
+func (c *mediaCache) set(key string, file *dto.MediaFile) {
+	ttl := time.Duration(file.TTL) * time.Second
+	if margin := ttl / 10; margin < mediaTTLMargin {
+		ttl -= margin
+	} else {
+		ttl -= mediaTTLMargin
+	}
+	if ttl <= 0 {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.items == nil {
+		c.items = make(map[string]mediaCacheItem)
+	}
+	now := time.Now()
+	// 写入时顺便清理过期的缓存
+	for k, item := range c.items {
+		if now.After(item.expireAt) {
+			delete(c.items, k)
+		}
+	}
+	c.items[key] = mediaCacheItem{file: copyMediaFile(file), expireAt: now.Add(ttl)}
+}
+
+func copyMediaFile(file *dto.MediaFile) *dto.MediaFile {
+	f := *file
+	f.FileInfo = append([]byte(nil), file.FileInfo...)
+	return &f
+}
@@ -0,0 +1,64 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tencent-connect/botgo/dto"
+	"github.com/tencent-connect/botgo/openapi"
+	"github.com/tencent-connect/botgo/openapi/options"
+	"golang.org/x/oauth2"
+)
+
+func TestUploadMedia(t *testing.T) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		media := &dto.RichMediaMessage{}
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(media))
+		assert.Equal(t, "aGVsbG8=", media.FileData)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"file_uuid":"uuid","file_info":"aW5mbw==","ttl":3600}`))
+	}))
+	defer server.Close()
+	client := (&openAPI{}).Setup("app", oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token"}), false)
+	api := client.(openapi.MediaAPI)
+	ctx := context.Background()
+
+	media, err := dto.NewRichMediaFromReader(dto.RichMediaImage, strings.NewReader("hello"))
+	assert.Nil(t, err)
+	file, err := api.UploadGroupMedia(ctx, "group", media, options.WithURL(server.URL))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("info"), file.MediaInfo().FileInfo)
+	assert.Equal(t, uint(3600), file.TTL)
+
+	// 有效期内相同目标的相同文件复用上传结果，修改返回的结果不影响缓存
+	file.FileInfo[0] = 'x'
+	cached, err := api.UploadGroupMedia(ctx, "group", media, options.WithURL(server.URL))
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+	assert.Equal(t, []byte("info"), cached.FileInfo)
+	assert.LessOrEqual(t, cached.TTL, uint(3600))
+
+	// 不同的群不能复用
+	_, err = api.UploadGroupMedia(ctx, "other", media, options.WithURL(server.URL))
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&count))
+
+	// 不同场景不能复用
+	_, err = api.UploadC2CMedia(ctx, "user", media, options.WithURL(server.URL))
+	assert.Nil(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&count))
+
+	// 直接发送的消息不缓存
+	media.SrvSendMsg = true
+	_, err = api.UploadC2CMedia(ctx, "user", media, options.WithURL(server.URL))
+	assert.Nil(t, err)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&count))
+}
@@ -202,6 +202,9 @@ func baseRequest(ctx context.Context, reqCMD *resty.Request, method, url string,
 	if opts.HideTip {
 		reqCMD = reqCMD.SetQueryParam("hidetip", "true")
 	}
+	if opts.Retry {
+		reqCMD = reqCMD.SetContext(withRetry(reqCMD.Context()))
+	}
 
 	return reqCMD.Execute(method, url)
 }
@@ -0,0 +1,33 @@
+package v1
+
+import (
+	"strconv"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/tencent-connect/botgo/constant"
+	"github.com/tencent-connect/botgo/openapi"
+)
+
+// recordResponseMeta 将响应信息记录到调用方通过 context 传入的 meta 中
+func recordResponseMeta(resp *resty.Response) {
+	meta, ok := openapi.ResponseMetaFromContext(resp.Request.Context())
+	if !ok {
+		return
+	}
+	header := resp.Header()
+	meta.TraceID = header.Get(constant.HeaderTraceID)
+	meta.StatusCode = resp.StatusCode()
+	meta.Latency = resp.Time()
+	meta.Attempts = resp.Request.Attempt
+	meta.Header = header
+	meta.RateLimit = openapi.RateLimitMeta{
+		Limit:      headerInt(header.Get(constant.HeaderRateLimitLimit)),
+		Remaining:  headerInt(header.Get(constant.HeaderRateLimitRemaining)),
+		RetryAfter: parseRetryAfter(header, resp.ReceivedAt()),
+	}
+}
+
+func headerInt(v string) int {
+	i, _ := strconv.Atoi(v)
+	return i
+}
@@ -0,0 +1,45 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tencent-connect/botgo/constant"
+	"github.com/tencent-connect/botgo/openapi"
+	"golang.org/x/oauth2"
+)
+
+func TestResponseMeta(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(constant.HeaderTraceID, r.URL.Query().Get("trace"))
+		w.Header().Set(constant.HeaderRateLimitLimit, "20")
+		w.Header().Set(constant.HeaderRateLimitRemaining, "19")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	api := (&openAPI{}).Setup("app", oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token"}), false)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			meta := &openapi.ResponseMeta{}
+			traceID := fmt.Sprintf("trace-%d", i)
+			ctx := openapi.WithResponseMeta(context.Background(), meta)
+			_, err := api.Transport(ctx, http.MethodGet, server.URL+"?trace="+traceID, nil)
+			assert.Nil(t, err)
+			assert.Equal(t, traceID, meta.TraceID)
+			assert.Equal(t, http.StatusOK, meta.StatusCode)
+			assert.Equal(t, 1, meta.Attempts)
+			assert.Equal(t, openapi.RateLimitMeta{Limit: 20, Remaining: 19}, meta.RateLimit)
+		}(i)
+	}
+	wg.Wait()
+	assert.Contains(t, api.TraceID(), "trace-")
+}
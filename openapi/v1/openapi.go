@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-resty/resty/v2" // resty 是一个优秀的 rest api 客户端，可以极大的减少开发基于 rest 标准接口求请求的封装工作量
@@ -14,6 +15,7 @@ import (
 	"github.com/tencent-connect/botgo/errs"
 	"github.com/tencent-connect/botgo/log"
 	"github.com/tencent-connect/botgo/openapi"
+	"github.com/tencent-connect/botgo/openapi/ratelimit"
 	"github.com/tencent-connect/botgo/version"
 	"golang.org/x/oauth2"
 )
@@ -26,9 +28,13 @@ type openAPI struct {
 	tokenSource oauth2.TokenSource
 	timeout     time.Duration
 
-	sandbox     bool   // 请求沙箱环境
-	debug       bool   // debug 模式，调试sdk时候使用
-	lastTraceID string // lastTraceID id
+	sandbox     bool         // 请求沙箱环境
+	debug       bool         // debug 模式，调试sdk时候使用
+	lastTraceID atomic.Value // lastTraceID id，会被并发的请求更新
+
+	retryPolicy *openapi.RetryPolicy // 重试策略，为空时不重试
+	limiter     *ratelimit.Limiter   // 本地限频器，为空时不限频
+	mediaCache  mediaCache           // 有效期内的富媒体上传结果
 
 	restyClient *resty.Client // resty client 复用
 }
@@ -45,7 +51,8 @@ func (o *openAPI) Version() openapi.APIVersion {
 
 // TraceID 获取 lastTraceID id
 func (o *openAPI) TraceID() string {
-	return o.lastTraceID
+	traceID, _ := o.lastTraceID.Load().(string)
+	return traceID
 }
 
 // Setup 生成一个实例
@@ -87,23 +94,31 @@ func (o *openAPI) setupClient(appID string) {
 		SetTimeout(o.timeout).
 		SetHeader("User-Agent", version.String()).
 		SetHeader("X-Union-Appid", appID).
+		AddRetryCondition(o.shouldRetry). // 是否重试由 retryPolicy 决定
+		SetRetryAfter(o.retryAfter).
 		SetPreRequestHook(
 			func(_ *resty.Client, request *http.Request) error {
 				// 执行请求前过滤器
 				// 由于在 `OnBeforeRequest` 的时候，request 还没生成，所以 filter 不能使用，所以放到 `PreRequestHook`
-				return openapi.DoReqFilterChains(request, nil)
+				if err := openapi.DoReqFilterChains(request, nil); err != nil {
+					return err
+				}
+				// 此时路径参数已经替换，可以区分限频的目标
+				return o.waitRateLimit(request)
 			},
 		).
+		OnBeforeRequest(o.markRoute). // 记录路由模板，用于本地限频
 		OnBeforeRequest(
-			func(c *resty.Client, _ *resty.Request) error {
+			func(_ *resty.Client, r *resty.Request) error {
 				tk, err := o.tokenSource.Token()
 				if err != nil {
 					log.Errorf("[setupClient] retrieve token failed:%s", err)
 					return err
 				}
-				c.SetAuthScheme(tk.TokenType)
+				// 设置在 request 上，client 被多个协程共用，不能修改 client 的配置
+				r.SetAuthScheme(tk.TokenType)
 				log.Debugf("token type:%s", tk.TokenType)
-				c.SetAuthToken(tk.AccessToken)
+				r.SetAuthToken(tk.AccessToken)
 				return nil
 			},
 		).
@@ -111,18 +126,21 @@ func (o *openAPI) setupClient(appID string) {
 		OnAfterResponse(
 			func(_ *resty.Client, resp *resty.Response) error {
 				log.Infof("%v", respInfo(resp))
+				recordResponseMeta(resp)
 				// 执行请求后过滤器
 				if err := openapi.DoRespFilterChains(resp.Request.RawRequest, resp.RawResponse); err != nil {
 					return err
 				}
 				traceID := resp.Header().Get(constant.HeaderTraceID)
-				o.lastTraceID = traceID
+				o.lastTraceID.Store(traceID)
 				// 非成功含义的状态码，需要返回 error 供调用方识别
+				var err error
 				if !openapi.IsSuccessStatus(resp.StatusCode()) {
-					o.handleError(resp)
-					return errs.New(resp.StatusCode(), string(resp.Body()), traceID)
+					err = errs.NewAPIError(resp.StatusCode(), resp.Body(), traceID)
+					o.handleError(err)
 				}
-				return nil
+				o.observeRateLimit(resp, err)
+				return err
 			},
 		)
 }
@@ -140,23 +158,10 @@ func (o *openAPI) GetAppID() string {
 	return o.appID
 }
 
-// errBody 请求出错情况下的body结构
-type errBody struct {
-	Message string `json:"message"`  // 错误原因
-	Code    int    `json:"code"`     // 错误码，后续废弃
-	ErrCode int    `json:"err_code"` // 错误码
-	TraceID string `json:"trace_id"` // 服务端traceID, 用于问题排查
-}
-
 // handleError 处理openapi调用失败的情况
-func (o *openAPI) handleError(resp *resty.Response) {
-	var b errBody
-	err := json.Unmarshal(resp.Body(), &b)
-	if err != nil {
-		log.Errorf("parse errBody fail, err:%v, body:%s", err, string(resp.Body()))
-		return
-	}
-	if b.ErrCode == errs.APICodeTokenExpireOrNotExist || b.Code == errs.APICodeTokenExpireOrNotExist {
+func (o *openAPI) handleError(err error) {
+	e := errs.Error(err)
+	if e.ErrCode() == errs.APICodeTokenExpireOrNotExist || e.APICode() == errs.APICodeTokenExpireOrNotExist {
 		log.Errorf("token expire or not exist, update token")
 		_, _ = o.tokenSource.Token()
 	}
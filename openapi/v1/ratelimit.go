@@ -0,0 +1,107 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/tencent-connect/botgo/errs"
+	"github.com/tencent-connect/botgo/openapi"
+	"github.com/tencent-connect/botgo/openapi/ratelimit"
+)
+
+// routeCtxKey 请求的路由，method 与 uri 模板
+type routeCtxKey struct{}
+
+// WithRateLimit 设置本地限频器，为 nil 时不限频
+func (o *openAPI) WithRateLimit(limiter *ratelimit.Limiter) openapi.OpenAPI {
+	o.limiter = limiter
+	return o
+}
+
+// markRoute 记录请求的路由，此时 url 还没有替换路径参数，是 uri 模板
+func (o *openAPI) markRoute(_ *resty.Client, r *resty.Request) error {
+	if o.limiter == nil {
+		return nil
+	}
+	route := ratelimit.Route(r.Method, routePath(r.URL))
+	r.SetContext(context.WithValue(r.Context(), routeCtxKey{}, route))
+	return nil
+}
+
+// waitRateLimit 请求发出之前等待令牌，重试的请求同样需要等待
+func (o *openAPI) waitRateLimit(request *http.Request) error {
+	limiter := o.limiter
+	if limiter == nil {
+		return nil
+	}
+	route, key, ok := rateLimitTarget(limiter, request)
+	if !ok {
+		return nil
+	}
+	return limiter.Wait(request.Context(), route, key)
+}
+
+// observeRateLimit 根据响应调整限频，被服务端限频时暂停对应的令牌桶，err 为根据响应创建的 *errs.Err
+func (o *openAPI) observeRateLimit(resp *resty.Response, err error) {
+	limiter := o.limiter
+	if limiter == nil || resp.RawResponse == nil {
+		return
+	}
+	route, key, ok := rateLimitTarget(limiter, resp.RawResponse.Request)
+	if !ok {
+		return
+	}
+	if openapi.IsSuccessStatus(resp.StatusCode()) {
+		limiter.Succeeded(route, key)
+		return
+	}
+	var codes []int
+	var e *errs.Err
+	if errors.As(err, &e) {
+		codes = []int{e.ErrCode(), e.APICode()}
+	}
+	if limiter.IsThrottled(resp.StatusCode(), codes...) {
+		limiter.Throttled(route, key, parseRetryAfter(resp.Header(), resp.ReceivedAt()))
+	}
+}
+
+// rateLimitTarget 获取请求对应的路由与令牌桶
+func rateLimitTarget(limiter *ratelimit.Limiter, request *http.Request) (string, string, bool) {
+	route, ok := request.Context().Value(routeCtxKey{}).(string)
+	if !ok {
+		return "", "", false
+	}
+	template := route[strings.Index(route, " ")+1:]
+	return route, limiter.Key(route, pathParams(template, request.URL.Path)), true
+}
+
+// pathParams 对比 uri 模板与实际的路径，获取路径参数
+func pathParams(template, path string) map[string]string {
+	tSegs := strings.Split(strings.Trim(template, "/"), "/")
+	pSegs := strings.Split(strings.Trim(path, "/"), "/")
+	if len(tSegs) != len(pSegs) {
+		return nil
+	}
+	params := make(map[string]string)
+	for i, seg := range tSegs {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			params[seg[1:len(seg)-1]] = pSegs[i]
+		}
+	}
+	return params
+}
+
+// routePath 去掉 url 中的协议与域名，只保留路径部分
+func routePath(url string) string {
+	if i := strings.Index(url, "://"); i >= 0 {
+		url = url[i+3:]
+		if j := strings.Index(url, "/"); j >= 0 {
+			return url[j:]
+		}
+		return "/"
+	}
+	return url
+}
@@ -0,0 +1,96 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tencent-connect/botgo/dto"
+	"github.com/tencent-connect/botgo/openapi"
+	"github.com/tencent-connect/botgo/openapi/options"
+	"github.com/tencent-connect/botgo/openapi/ratelimit"
+	"golang.org/x/oauth2"
+)
+
+func TestPathParams(t *testing.T) {
+	assert.Equal(t, "/channels/{channel_id}/messages", routePath("https://api.sgroup.qq.com/channels/{channel_id}/messages"))
+	assert.Equal(t, map[string]string{"group_id": "g1", "message_id": "m1"},
+		pathParams(string(retractGroupMessageURI), "/v2/groups/g1/messages/m1"))
+	assert.Nil(t, pathParams(string(messagesURI), "/users/@me"))
+}
+
+func TestRateLimit(t *testing.T) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	limiter := ratelimit.New(ratelimit.Config{
+		Routes: map[string]ratelimit.Limit{
+			ratelimit.Route(http.MethodPost, string(messagesURI)): {Rate: 10, Burst: 1},
+		},
+	})
+	client := (&openAPI{}).Setup("app", oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token"}), false)
+	api := client.(openapi.RateLimitConfigurer).WithRateLimit(limiter)
+	// 保留路径模板，路由与线上接口一致
+	url := options.WithURL(server.URL + string(messagesURI))
+	ctx := context.Background()
+	msg := &dto.MessageToCreate{Content: "hello"}
+
+	// 同一个子频道的突发请求需要排队，桶容量为 1，第三个请求至少等待 200ms
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err := api.PostMessage(ctx, "c1", msg, url)
+		assert.Nil(t, err)
+	}
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(180*time.Millisecond))
+
+	// 其他子频道不受影响
+	start = time.Now()
+	_, err := api.PostMessage(ctx, "c2", msg, url)
+	assert.Nil(t, err)
+	assert.Less(t, int64(time.Since(start)), int64(50*time.Millisecond))
+	assert.Equal(t, int32(4), atomic.LoadInt32(&count))
+}
+
+func TestRateLimit_ErrCode(t *testing.T) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if atomic.AddInt32(&count, 1) == 1 {
+			w.Header().Set("Retry-After", "0.2")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"message":"push message frequency limit","code":22009,"err_code":22009}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	route := ratelimit.Route(http.MethodPost, string(messagesURI))
+	limiter := ratelimit.New(ratelimit.Config{
+		Routes:   map[string]ratelimit.Limit{route: {Rate: 100, Burst: 10}},
+		ErrCodes: ratelimit.DefaultConfig.ErrCodes,
+	})
+	client := (&openAPI{}).Setup("app", oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token"}), false)
+	api := client.(openapi.RateLimitConfigurer).WithRateLimit(limiter)
+	url := options.WithURL(server.URL + string(messagesURI))
+	ctx := context.Background()
+	msg := &dto.MessageToCreate{Content: "hello"}
+
+	// 消息发送超频的错误码同样暂停令牌桶
+	_, err := api.PostMessage(ctx, "c1", msg, url)
+	assert.NotNil(t, err)
+	start := time.Now()
+	_, err = api.PostMessage(ctx, "c1", msg, url)
+	assert.Nil(t, err)
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(150*time.Millisecond))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&count))
+}
@@ -0,0 +1,130 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/tencent-connect/botgo/constant"
+	"github.com/tencent-connect/botgo/errs"
+	"github.com/tencent-connect/botgo/log"
+	"github.com/tencent-connect/botgo/openapi"
+)
+
+// retryCtxKey 标记非幂等请求开启了重试
+type retryCtxKey struct{}
+
+// withRetry 在请求的 context 上标记允许重试
+func withRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retryCtxKey{}, true)
+}
+
+// WithRetry 设置接口调用失败时的重试策略，为 nil 时不重试
+func (o *openAPI) WithRetry(policy *openapi.RetryPolicy) openapi.OpenAPI {
+	o.retryPolicy = policy
+	count := 0
+	if policy != nil && policy.MaxAttempts > 1 {
+		count = policy.MaxAttempts - 1
+		if policy.WaitTime > 0 {
+			o.restyClient.SetRetryWaitTime(policy.WaitTime)
+		}
+		if policy.MaxWaitTime > 0 {
+			o.restyClient.SetRetryMaxWaitTime(policy.MaxWaitTime)
+		}
+	}
+	o.restyClient.SetRetryCount(count)
+	return o
+}
+
+// shouldRetry 判断失败的请求是否需要重试
+func (o *openAPI) shouldRetry(resp *resty.Response, err error) bool {
+	policy := o.retryPolicy
+	if policy == nil || resp == nil || resp.Request == nil {
+		return false
+	}
+	if !openapi.IsIdempotentMethod(resp.Request.Method) {
+		if retry, _ := resp.Request.Context().Value(retryCtxKey{}).(bool); !retry {
+			return false
+		}
+	}
+	// 没有收到响应，网络错误或者超时
+	if resp.RawResponse == nil {
+		return err != nil && policy.NetworkError
+	}
+	if openapi.IsSuccessStatus(resp.StatusCode()) {
+		return false
+	}
+	retry := containsCode(policy.StatusCodes, resp.StatusCode()) || isRetriableErrCode(policy, err)
+	if retry {
+		log.Warnf("[OPENAPI]%v %v failed, attempt: %d, status: %v, will retry",
+			resp.Request.Method, resp.Request.URL, resp.Request.Attempt, resp.Status())
+	}
+	return retry
+}
+
+// isRetriableErrCode 平台错误码是否需要重试，err 为 OnAfterResponse 中根据响应创建的 *errs.Err
+func isRetriableErrCode(policy *openapi.RetryPolicy, err error) bool {
+	if len(policy.ErrCodes) == 0 {
+		return false
+	}
+	var e *errs.Err
+	if !errors.As(err, &e) {
+		return false
+	}
+	return containsCode(policy.ErrCodes, e.ErrCode()) || containsCode(policy.ErrCodes, e.APICode())
+}
+
+func containsCode(codes []int, code int) bool {
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// retryAfter 根据限频相关的响应头计算重试前的等待时间，返回 0 时使用默认的退避算法
+// 要求等待的时间超过策略的最大等待时间时，不再重试
+func (o *openAPI) retryAfter(_ *resty.Client, resp *resty.Response) (time.Duration, error) {
+	wait := parseRetryAfter(resp.Header(), time.Now())
+	if wait <= 0 {
+		return 0, nil
+	}
+	if policy := o.retryPolicy; policy != nil && policy.MaxWaitTime > 0 && wait > policy.MaxWaitTime {
+		return 0, fmt.Errorf("retry after %s exceeds max wait time %s", wait, policy.MaxWaitTime)
+	}
+	return wait, nil
+}
+
+// parseRetryAfter 解析限频相关的响应头，依次尝试 Retry-After，X-RateLimit-Reset-After，X-RateLimit-Reset
+func parseRetryAfter(header http.Header, now time.Time) time.Duration {
+	if v := header.Get(constant.HeaderRetryAfter); v != "" {
+		if sec, err := strconv.ParseFloat(v, 64); err == nil {
+			return secondsToDuration(sec)
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return t.Sub(now)
+		}
+	}
+	if v := header.Get(constant.HeaderRateLimitResetAfter); v != "" {
+		if sec, err := strconv.ParseFloat(v, 64); err == nil {
+			return secondsToDuration(sec)
+		}
+	}
+	if v := header.Get(constant.HeaderRateLimitReset); v != "" {
+		if sec, err := strconv.ParseFloat(v, 64); err == nil {
+			whole, frac := math.Modf(sec)
+			return time.Unix(int64(whole), int64(frac*float64(time.Second))).Sub(now)
+		}
+	}
+	return 0
+}
+
+func secondsToDuration(sec float64) time.Duration {
+	return time.Duration(sec * float64(time.Second))
+}
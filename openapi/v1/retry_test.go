@@ -0,0 +1,88 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tencent-connect/botgo/openapi"
+	"github.com/tencent-connect/botgo/openapi/options"
+	"golang.org/x/oauth2"
+)
+
+func TestRetry(t *testing.T) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch n := atomic.AddInt32(&count, 1); {
+		case n == 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case n == 2:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"code":12345,"message":"busy"}`))
+		default:
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	client := (&openAPI{}).Setup("app", oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token"}), false)
+	api := client.(openapi.RetryConfigurer).WithRetry(&openapi.RetryPolicy{
+		MaxAttempts: 3,
+		WaitTime:    time.Millisecond,
+		MaxWaitTime: 10 * time.Millisecond,
+		StatusCodes: []int{http.StatusServiceUnavailable},
+		ErrCodes:    []int{12345},
+	}).(*openAPI)
+	ctx := context.Background()
+
+	t.Run("idempotent", func(t *testing.T) {
+		atomic.StoreInt32(&count, 0)
+		_, err := baseRequest(ctx, api.request(ctx), http.MethodGet, server.URL)
+		assert.Nil(t, err)
+		assert.Equal(t, int32(3), atomic.LoadInt32(&count))
+	})
+	t.Run("not idempotent", func(t *testing.T) {
+		atomic.StoreInt32(&count, 0)
+		_, err := baseRequest(ctx, api.request(ctx), http.MethodPost, server.URL)
+		assert.NotNil(t, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+	})
+	t.Run("opt in", func(t *testing.T) {
+		atomic.StoreInt32(&count, 0)
+		_, err := baseRequest(ctx, api.request(ctx), http.MethodPost, server.URL, options.WithRetry())
+		assert.Nil(t, err)
+		assert.Equal(t, int32(3), atomic.LoadInt32(&count))
+	})
+	t.Run("disabled", func(t *testing.T) {
+		atomic.StoreInt32(&count, 0)
+		api.WithRetry(nil)
+		_, err := baseRequest(ctx, api.request(ctx), http.MethodGet, server.URL)
+		assert.NotNil(t, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+	}{
+		{"none", http.Header{}, 0},
+		{"retry after seconds", http.Header{"Retry-After": {"2"}}, 2 * time.Second},
+		{"retry after date", http.Header{"Retry-After": {now.Add(3 * time.Second).UTC().Format(http.TimeFormat)}},
+			3 * time.Second},
+		{"reset after", http.Header{"X-Ratelimit-Reset-After": {"1.5"}}, 1500 * time.Millisecond},
+		{"reset", http.Header{"X-Ratelimit-Reset": {"1700000004"}}, 4 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseRetryAfter(tt.header, now))
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package botgo
 
 import (
+	"context"
+
 	"github.com/tencent-connect/botgo/dto"
 	"github.com/tencent-connect/botgo/sessions/local"
 	"golang.org/x/oauth2"
@@ -14,4 +16,7 @@ var defaultSessionManager SessionManager = local.New()
 type SessionManager interface {
 	// Start 启动连接，默认使用 apInfo 中的 shards 作为 shard 数量，如果有需要自己指定 shard 数，请修 apInfo 中的信息
 	Start(apInfo *dto.WebsocketAP, tokenSource oauth2.TokenSource, intents *dto.Intent) error
+	// Stop 停止重连，优雅关闭所有连接，等待已经收到的事件处理完成，所有 shard 退出后返回，等待时间受 ctx 控制
+	// Stop 返回之后，阻塞中的 Start 也会返回
+	Stop(ctx context.Context) error
 }
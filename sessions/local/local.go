@@ -2,6 +2,7 @@
 package local
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -13,22 +14,40 @@ import (
 )
 
 // New 创建本地session管理器
-func New() *ChanManager {
-	return &ChanManager{}
+func New(opts ...Option) *ChanManager {
+	l := &ChanManager{
+		tracker:     manager.NewTracker(),
+		reconnector: &manager.Reconnector{},
+		limiter:     manager.NewSessionLimiter(nil),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
 }
 
 // ChanManager 默认的本地 session manager 实现
 type ChanManager struct {
 	sessionChan chan dto.Session
+	wsClient    websocket.WebSocket     // 用于创建连接的 websocket 实现，为空时使用 websocket.ClientImpl
+	tracker     *manager.Tracker        // 跟踪正在运行的连接，用于优雅关闭
+	reconnector *manager.Reconnector    // 按 shard 计算重连的退避时间
+	limiter     *manager.SessionLimiter // 本地维护的 session 启动额度
 }
 
 // Start 启动本地 session manager
 func (l *ChanManager) Start(apInfo *dto.WebsocketAP, tokenSource oauth2.TokenSource, intents *dto.Intent) error {
 	defer log.Sync()
-	if err := manager.CheckSessionLimit(apInfo); err != nil {
+	// 额度不足时等待重置，而不是直接退出
+	limited, err := l.limiter.Start(l.tracker.Context(), apInfo)
+	if err != nil {
+		if l.tracker.Stopped() {
+			return nil
+		}
 		log.Errorf("[ws/session/local] session limited apInfo: %+v", apInfo)
 		return err
 	}
+	apInfo = limited
 	startInterval := manager.CalcInterval(apInfo.SessionStartLimit.MaxConcurrency)
 	log.Infof("[ws/session/local] will start %d sessions and per session start interval is %s",
 		apInfo.Shards, startInterval)
@@ -49,12 +68,47 @@ func (l *ChanManager) Start(apInfo *dto.WebsocketAP, tokenSource oauth2.TokenSou
 		l.sessionChan <- session
 	}
 
-	for session := range l.sessionChan {
-		// MaxConcurrency 代表的是每 5s 可以连多少个请求
-		time.Sleep(startInterval)
-		go l.newConnect(session)
+	for {
+		select {
+		case <-l.tracker.Done():
+			log.Infof("[ws/session/local] session manager stopped")
+			return nil
+		case session := <-l.sessionChan:
+			// MaxConcurrency 代表的是每 5s 可以连多少个请求
+			if !l.tracker.Sleep(startInterval) {
+				continue
+			}
+			l.tracker.Go(func() { l.newConnect(session) })
+		}
+	}
+}
+
+// Stats 获取当前实例所有连接的运行状态，包括 shard，心跳延迟等
+func (l *ChanManager) Stats() []websocket.Stats {
+	return l.tracker.Stats()
+}
+
+// Stop 停止本地 session manager，不再重连，优雅关闭所有连接，等待所有 shard 退出后返回，等待时间受 ctx 控制
+func (l *ChanManager) Stop(ctx context.Context) error {
+	return l.tracker.Stop(ctx)
+}
+
+// requeue 将 session 放回 sessionChan 排队重连，manager 已经停止的情况下丢弃
+func (l *ChanManager) requeue(session dto.Session) {
+	if l.tracker.Stopped() {
+		log.Infof("%s session manager stopped, will not reconnect", &session)
+		return
+	}
+	l.sessionChan <- session
+}
+
+// retry 连接失败，按照退避策略等待之后再放回队列重连
+func (l *ChanManager) retry(session dto.Session, delay time.Duration) {
+	if delay > 0 {
+		log.Warnf("%s will reconnect after %s", &session, delay)
+		l.tracker.Sleep(delay)
 	}
-	return nil
+	l.requeue(session)
 }
 
 // newConnect 启动一个新的连接，如果连接在监听过程中报错了，或者被远端关闭了链接，需要识别关闭的原因，能否继续 resume
@@ -66,13 +120,21 @@ func (l *ChanManager) newConnect(session dto.Session) {
 		// panic 留下日志，放回 session
 		if err := recover(); err != nil {
 			websocket.PanicHandler(err, &session)
-			l.sessionChan <- session
+			l.retry(session, l.reconnector.Failed(session.Shards, fmt.Errorf("panic: %v", err)))
 		}
 	}()
-	wsClient := websocket.ClientImpl.New(session)
+	// identify 会消耗一次启动额度，额度耗尽时在连接之前等待重置，resume 不消耗
+	if session.ID == "" {
+		if err := l.limiter.Take(l.tracker.Context()); err != nil {
+			l.requeue(session)
+			return
+		}
+	}
+	wsClient := l.newClient(session)
 	if err := wsClient.Connect(); err != nil {
 		log.Error(err)
-		l.sessionChan <- session // 连接失败，丢回去队列排队重连
+		// 连接失败，退避之后丢回去队列排队重连
+		l.retry(session, l.reconnector.Failed(session.Shards, err))
 		return
 	}
 	var err error
@@ -85,10 +147,21 @@ func (l *ChanManager) newConnect(session dto.Session) {
 	}
 	if err != nil {
 		log.Errorf("[ws/session] Identify/Resume err %+v", err)
+		wsClient.Close()
+		l.retry(session, l.reconnector.Failed(session.Shards, err))
 		return
 	}
+	connectedAt := time.Now()
+	if !l.tracker.Add(wsClient) {
+		wsClient.Close()
+		return
+	}
+	defer l.tracker.Remove(wsClient)
 	if err = wsClient.Listening(); err != nil {
 		log.Errorf("[ws/session] Listening err %+v", err)
+		if l.tracker.Stopped() {
+			return
+		}
 		currentSession := wsClient.Session()
 		// 对于不能够进行重连的session，需要清空 session id 与 seq
 		if manager.CanNotResume(err) {
@@ -102,7 +175,16 @@ func (l *ChanManager) newConnect(session dto.Session) {
 			panic(msg) // 当机器人被下架，或者封禁，将不能再连接，所以 panic
 		}
 		// 将 session 放到 session chan 中，用于启动新的连接，当前连接退出
-		l.sessionChan <- *currentSession
+		// 连接未能稳定运行就断开的，视为连续失败，需要退避
+		l.retry(*currentSession, l.reconnector.Disconnected(session.Shards, connectedAt, err))
 		return
 	}
 }
+
+// newClient 使用指定的 websocket 实现创建连接，未指定时使用全局注册的实现
+func (l *ChanManager) newClient(session dto.Session) websocket.WebSocket {
+	if l.wsClient != nil {
+		return l.wsClient.New(session)
+	}
+	return websocket.ClientImpl.New(session)
+}
@@ -0,0 +1,43 @@
+package local
+
+import (
+	"time"
+
+	"github.com/tencent-connect/botgo/openapi"
+	"github.com/tencent-connect/botgo/sessions/manager"
+	"github.com/tencent-connect/botgo/websocket"
+)
+
+// Option is a function that configures a ChanManager.
+type Option func(manager *ChanManager)
+
+// WithWebsocketClient 指定创建连接所使用的 websocket 实现，默认为 websocket.ClientImpl
+// 配合 client.NewWithDispatcher 使用，可以让不同的机器人使用各自的事件分发器
+func WithWebsocketClient(ws websocket.WebSocket) Option {
+	return func(m *ChanManager) {
+		m.wsClient = ws
+	}
+}
+
+// WithBackoff 指定重连的退避策略，默认为 manager.DefaultBackoff
+func WithBackoff(backoff manager.Backoff) Option {
+	return func(m *ChanManager) {
+		m.reconnector.Backoff = backoff
+	}
+}
+
+// WithShardFailingHandler 指定 shard 连续失败超过 threshold 时的回调，可用于告警
+func WithShardFailingHandler(threshold time.Duration, handler manager.ShardFailingHandler) Option {
+	return func(m *ChanManager) {
+		m.reconnector.FailingThreshold = threshold
+		m.reconnector.OnShardFailing = handler
+	}
+}
+
+// WithWebsocketAPI 指定用于拉取 /gateway/bot 的接口，session 启动额度不足时，等待重置之后重新拉取频控信息
+// 未指定时，认为重置之后额度恢复为 Total
+func WithWebsocketAPI(api openapi.WebsocketAPI) Option {
+	return func(m *ChanManager) {
+		m.limiter = manager.NewSessionLimiter(api)
+	}
+}
@@ -0,0 +1,149 @@
+package manager
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/tencent-connect/botgo/dto"
+)
+
+// Backoff 重连退避策略
+type Backoff interface {
+	// Next 返回连续第 attempt 次（从 1 开始）失败之后，到下一次重连需要等待的时间
+	Next(attempt int) time.Duration
+}
+
+const (
+	// defaultBackoffMax 默认的最大等待时间，ExponentialBackoff.Max 小于等于 0 时同样使用该值
+	defaultBackoffMax = 2 * time.Minute
+	// maxBackoffAttempt 计算等待时间时 attempt 的上限，避免指数运算溢出
+	maxBackoffAttempt = 64
+)
+
+// DefaultBackoff 默认的退避策略，1s 开始指数增长，最大 2min，20% 抖动
+var DefaultBackoff Backoff = &ExponentialBackoff{
+	Base:   time.Second,
+	Max:    defaultBackoffMax,
+	Factor: 2,
+	Jitter: 0.2,
+}
+
+// ExponentialBackoff 带抖动的指数退避，等待时间为 Base * Factor^(attempt-1)，不超过 Max
+type ExponentialBackoff struct {
+	Base   time.Duration // 首次失败的等待时间
+	Max    time.Duration // 最大等待时间，小于等于 0 时为 2min
+	Factor float64       // 增长因子，小于等于 1 时按 2 处理
+	Jitter float64       // 抖动比例，0-1，实际等待时间在 [d*(1-Jitter), d*(1+Jitter)] 之间，避免多个 shard 同时重连
+
+	lock sync.Mutex
+	rand *rand.Rand
+}
+
+// Next 计算等待时间
+func (b *ExponentialBackoff) Next(attempt int) time.Duration {
+	if attempt <= 0 {
+		return 0
+	}
+	if attempt > maxBackoffAttempt {
+		attempt = maxBackoffAttempt
+	}
+	factor := b.Factor
+	if factor <= 1 {
+		factor = 2
+	}
+	limit := float64(b.Max)
+	if b.Max <= 0 {
+		limit = float64(defaultBackoffMax)
+	}
+	d := math.Min(float64(b.Base)*math.Pow(factor, float64(attempt-1)), limit)
+	if b.Jitter > 0 {
+		d += d * b.Jitter * (2*b.float64() - 1)
+	}
+	return time.Duration(math.Min(d, limit))
+}
+
+func (b *ExponentialBackoff) float64() float64 {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.rand == nil {
+		b.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return b.rand.Float64()
+}
+
+// ShardFailingHandler shard 持续失败超过阈值时的回调，每一轮连续失败只回调一次
+// since 为本轮第一次失败的时间，attempts 为连续失败次数，err 为最近一次的错误
+type ShardFailingHandler func(shard dto.ShardConfig, since time.Time, attempts int, err error)
+
+// DefaultStableDuration 连接持续超过该时长后断开，视为连接成功过，不计入连续失败
+const DefaultStableDuration = time.Minute
+
+// Reconnector 按 shard 记录连续失败的情况，根据退避策略计算重连等待时间
+type Reconnector struct {
+	Backoff          Backoff             // 退避策略，为空时使用 DefaultBackoff
+	StableDuration   time.Duration       // 连接稳定的时长，为 0 时使用 DefaultStableDuration
+	FailingThreshold time.Duration       // 持续失败超过该时长时回调 OnShardFailing，为 0 时不回调
+	OnShardFailing   ShardFailingHandler // shard 持续失败的回调
+
+	lock   sync.Mutex
+	shards map[uint32]*shardFailure
+}
+
+type shardFailure struct {
+	since    time.Time
+	attempts int
+	notified bool
+}
+
+// Failed 记录 shard 的一次失败，返回下一次重连前需要等待的时间
+func (r *Reconnector) Failed(shard dto.ShardConfig, err error) time.Duration {
+	r.lock.Lock()
+	if r.shards == nil {
+		r.shards = make(map[uint32]*shardFailure)
+	}
+	f, ok := r.shards[shard.ShardID]
+	if !ok {
+		f = &shardFailure{since: time.Now()}
+		r.shards[shard.ShardID] = f
+	}
+	f.attempts++
+	attempts, since := f.attempts, f.since
+	notify := !f.notified && r.OnShardFailing != nil && r.FailingThreshold > 0 &&
+		time.Since(f.since) >= r.FailingThreshold
+	if notify {
+		f.notified = true
+	}
+	r.lock.Unlock()
+
+	if notify {
+		r.OnShardFailing(shard, since, attempts, err)
+	}
+	backoff := r.Backoff
+	if backoff == nil {
+		backoff = DefaultBackoff
+	}
+	return backoff.Next(attempts)
+}
+
+// Succeeded 记录 shard 连接成功，清空失败记录
+func (r *Reconnector) Succeeded(shard dto.ShardConfig) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.shards, shard.ShardID)
+}
+
+// Disconnected 记录 shard 的连接断开，connectedAt 为连接建立的时间
+// 连接已经稳定运行过的，清空失败记录并立即重连，否则视为一次失败，返回需要等待的时间
+func (r *Reconnector) Disconnected(shard dto.ShardConfig, connectedAt time.Time, err error) time.Duration {
+	stable := r.StableDuration
+	if stable <= 0 {
+		stable = DefaultStableDuration
+	}
+	if time.Since(connectedAt) >= stable {
+		r.Succeeded(shard)
+		return 0
+	}
+	return r.Failed(shard, err)
+}
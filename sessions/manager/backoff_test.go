@@ -0,0 +1,58 @@
+package manager
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tencent-connect/botgo/dto"
+)
+
+func TestExponentialBackoff_Next(t *testing.T) {
+	b := &ExponentialBackoff{Base: time.Second, Max: 10 * time.Second, Factor: 2}
+	assert.Equal(t, time.Duration(0), b.Next(0))
+	assert.Equal(t, time.Second, b.Next(1))
+	assert.Equal(t, 4*time.Second, b.Next(3))
+	assert.Equal(t, 10*time.Second, b.Next(10))
+
+	b.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := b.Next(2)
+		assert.True(t, d >= time.Second && d <= 3*time.Second, d)
+	}
+
+	// 没有设置 Max 时使用默认的上限，attempt 很大时也不会溢出
+	b = &ExponentialBackoff{Base: time.Second, Factor: 10}
+	assert.Equal(t, defaultBackoffMax, b.Next(1000))
+	assert.Equal(t, defaultBackoffMax, b.Next(math.MaxInt32))
+}
+
+func TestReconnector(t *testing.T) {
+	var notified int
+	r := &Reconnector{
+		Backoff:          &ExponentialBackoff{Base: time.Second, Max: time.Minute},
+		FailingThreshold: time.Nanosecond,
+		OnShardFailing: func(shard dto.ShardConfig, since time.Time, attempts int, err error) {
+			notified++
+			assert.Equal(t, uint32(1), shard.ShardID)
+		},
+	}
+	shard := dto.ShardConfig{ShardID: 1, ShardCount: 2}
+	other := dto.ShardConfig{ShardID: 0, ShardCount: 2}
+	err := errors.New("connect failed")
+
+	assert.Equal(t, time.Second, r.Failed(shard, err))
+	assert.Equal(t, 2*time.Second, r.Failed(shard, err))
+	assert.Equal(t, 4*time.Second, r.Failed(shard, err))
+	// 每一轮连续失败只回调一次
+	assert.Equal(t, 1, notified)
+
+	// 连接稳定运行过，清空失败记录
+	assert.Equal(t, time.Duration(0), r.Disconnected(shard, time.Now().Add(-time.Hour), err))
+	r.OnShardFailing = nil
+	assert.Equal(t, time.Second, r.Disconnected(shard, time.Now(), err))
+	// 不同 shard 之间互不影响
+	assert.Equal(t, time.Second, r.Failed(other, err))
+}
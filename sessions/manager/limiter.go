@@ -0,0 +1,155 @@
+package manager
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/tencent-connect/botgo/dto"
+	"github.com/tencent-connect/botgo/errs"
+	"github.com/tencent-connect/botgo/log"
+	"github.com/tencent-connect/botgo/openapi"
+)
+
+const (
+	// defaultResetPeriod 未配置 api 时，额度重置后认为下一次重置的周期
+	defaultResetPeriod = 24 * time.Hour
+	// minResetWait 额度不足时最少的等待时间，避免 reset_after 为 0 时频繁拉取接入点
+	minResetWait = time.Second
+)
+
+// SessionLimiter 在本地维护 session 的启动额度，额度不足时等待重置，保证启动次数不会超过 SessionStartLimit
+// 只有 identify 会消耗额度，resume 不消耗
+type SessionLimiter struct {
+	api openapi.WebsocketAPI // 额度重置后用于重新拉取 /gateway/bot，为空时认为重置后额度恢复为 Total
+
+	lock    sync.Mutex
+	limit   dto.SessionStartLimit
+	resetAt time.Time
+}
+
+// NewSessionLimiter 创建 session 启动额度控制器
+func NewSessionLimiter(api openapi.WebsocketAPI) *SessionLimiter {
+	return &SessionLimiter{api: api}
+}
+
+// Start 等待额度足够启动 apInfo 中所有的 shard，返回更新了频控信息的接入点，其他字段保持不变
+// 额度不足时等待 ResetAfter 后重新拉取，等待过程受 ctx 控制
+func (l *SessionLimiter) Start(ctx context.Context, apInfo *dto.WebsocketAP) (*dto.WebsocketAP, error) {
+	ap := *apInfo
+	for {
+		l.reset(ap.SessionStartLimit)
+		limit := ap.SessionStartLimit
+		if ap.Shards <= limit.Remaining {
+			return &ap, nil
+		}
+		// shard 数量超过了总额度，或者没有额度（包括重新拉取到的额度），等待也无法启动
+		if limit.Total == 0 || ap.Shards > limit.Total {
+			return nil, errs.ErrSessionLimit
+		}
+		wait := resetWait(limit)
+		log.Warnf("[ws/session] session limited, shards: %d, remaining: %d, will retry after %s",
+			ap.Shards, limit.Remaining, wait)
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+		if err := l.refresh(ctx); err != nil {
+			return nil, err
+		}
+		ap.SessionStartLimit = l.snapshot()
+	}
+}
+
+// Take 消耗一次启动额度，额度耗尽时等待重置，等待过程受 ctx 控制
+func (l *SessionLimiter) Take(ctx context.Context) error {
+	for {
+		l.lock.Lock()
+		if l.limit.Remaining > 0 {
+			l.limit.Remaining--
+			l.lock.Unlock()
+			return nil
+		}
+		if l.limit.Total == 0 { // 没有额度，等待重置也无法恢复
+			l.lock.Unlock()
+			return errs.ErrSessionLimit
+		}
+		wait := time.Until(l.resetAt)
+		l.lock.Unlock()
+
+		if wait < minResetWait {
+			wait = minResetWait
+		}
+		log.Warnf("[ws/session] session start budget exhausted, will retry after %s", wait)
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+		if err := l.refresh(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// Remaining 本地剩余的启动额度
+func (l *SessionLimiter) Remaining() uint32 {
+	return l.snapshot().Remaining
+}
+
+func (l *SessionLimiter) snapshot() dto.SessionStartLimit {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.limit
+}
+
+func (l *SessionLimiter) reset(limit dto.SessionStartLimit) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.limit = limit
+	l.resetAt = time.Now().Add(time.Duration(limit.ResetAfter) * time.Millisecond)
+}
+
+// refresh 重置时间到达后，重新拉取频控信息，拉取失败时保留本地额度，等待下一次重试
+// 拉取到的额度为 0 时，等待重置也无法启动，返回 errs.ErrSessionLimit
+func (l *SessionLimiter) refresh(ctx context.Context) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if time.Now().Before(l.resetAt) {
+		return nil
+	}
+	if l.api == nil {
+		l.limit.Remaining = l.limit.Total
+		l.resetAt = time.Now().Add(defaultResetPeriod)
+		return nil
+	}
+	apInfo, err := l.api.WS(ctx, nil, "")
+	if err != nil {
+		log.Errorf("[ws/session] refresh session start limit failed: %v", err)
+		return nil
+	}
+	limit := apInfo.SessionStartLimit
+	if limit.Total == 0 || limit.MaxConcurrency == 0 {
+		log.Errorf("[ws/session] invalid session start limit: %+v", limit)
+		return errs.ErrSessionLimit
+	}
+	l.limit = limit
+	l.resetAt = time.Now().Add(time.Duration(l.limit.ResetAfter) * time.Millisecond)
+	return nil
+}
+
+func resetWait(limit dto.SessionStartLimit) time.Duration {
+	wait := time.Duration(limit.ResetAfter) * time.Millisecond
+	if wait < minResetWait {
+		wait = minResetWait
+	}
+	return wait
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,89 @@
+package manager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tencent-connect/botgo/dto"
+	"github.com/tencent-connect/botgo/errs"
+)
+
+type fakeWebsocketAPI struct {
+	calls int
+	limit dto.SessionStartLimit
+}
+
+func (f *fakeWebsocketAPI) WS(_ context.Context, _ map[string]string, _ string) (*dto.WebsocketAP, error) {
+	f.calls++
+	return &dto.WebsocketAP{SessionStartLimit: f.limit}, nil
+}
+
+func TestSessionLimiter_Start(t *testing.T) {
+	api := &fakeWebsocketAPI{limit: dto.SessionStartLimit{Total: 10, Remaining: 10, ResetAfter: 1000,
+		MaxConcurrency: 1}}
+	l := NewSessionLimiter(api)
+	apInfo := &dto.WebsocketAP{
+		URL:               "wss://example",
+		Shards:            2,
+		SessionStartLimit: dto.SessionStartLimit{Total: 10, Remaining: 1, ResetAfter: 1},
+	}
+	got, err := l.Start(context.Background(), apInfo)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, api.calls)
+	assert.Equal(t, "wss://example", got.URL)
+	assert.Equal(t, uint32(2), got.Shards)
+	assert.Equal(t, uint32(10), got.SessionStartLimit.Remaining)
+
+	// shard 数量超过总额度，直接返回错误
+	apInfo.Shards = 11
+	_, err = l.Start(context.Background(), apInfo)
+	assert.Equal(t, errs.ErrSessionLimit, err)
+
+	// 没有总额度，重置后也无法恢复，不能一直等待
+	apInfo.Shards = 2
+	apInfo.SessionStartLimit = dto.SessionStartLimit{Remaining: 1, ResetAfter: 1}
+	_, err = NewSessionLimiter(nil).Start(context.Background(), apInfo)
+	assert.Equal(t, errs.ErrSessionLimit, err)
+
+	// 重新拉取到的总额度不足
+	api = &fakeWebsocketAPI{limit: dto.SessionStartLimit{Total: 1, Remaining: 1, MaxConcurrency: 1}}
+	apInfo.SessionStartLimit = dto.SessionStartLimit{Total: 10, Remaining: 1, ResetAfter: 1}
+	_, err = NewSessionLimiter(api).Start(context.Background(), apInfo)
+	assert.Equal(t, errs.ErrSessionLimit, err)
+	assert.Equal(t, 1, api.calls)
+}
+
+func TestSessionLimiter_Take(t *testing.T) {
+	l := NewSessionLimiter(nil)
+	l.reset(dto.SessionStartLimit{Total: 2, Remaining: 1, ResetAfter: 1})
+	assert.Nil(t, l.Take(context.Background()))
+	assert.Equal(t, uint32(0), l.Remaining())
+
+	// 额度耗尽，等待重置后恢复为 Total
+	assert.Nil(t, l.Take(context.Background()))
+	assert.Equal(t, uint32(1), l.Remaining())
+
+	// 额度耗尽且未到重置时间，等待受 ctx 控制
+	assert.Nil(t, l.Take(context.Background()))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, l.Take(ctx))
+
+	// 没有总额度，直接返回错误
+	l.reset(dto.SessionStartLimit{})
+	assert.Equal(t, errs.ErrSessionLimit, l.Take(context.Background()))
+
+	// 重新拉取到的额度无效，返回错误而不是一直等待
+	for _, limit := range []dto.SessionStartLimit{
+		{Remaining: 1, ResetAfter: 1, MaxConcurrency: 1},
+		{Total: 10, Remaining: 1, ResetAfter: 1},
+	} {
+		api := &fakeWebsocketAPI{limit: limit}
+		l = NewSessionLimiter(api)
+		l.reset(dto.SessionStartLimit{Total: 10, ResetAfter: 1, MaxConcurrency: 1})
+		assert.Equal(t, errs.ErrSessionLimit, l.Take(context.Background()))
+		assert.Equal(t, 1, api.calls)
+	}
+}
@@ -0,0 +1,148 @@
+package manager
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/tencent-connect/botgo/log"
+	"github.com/tencent-connect/botgo/websocket"
+)
+
+// Tracker 跟踪 session manager 中正在运行的连接，用于停止时关闭所有连接并等待所有 shard 退出
+type Tracker struct {
+	lock     sync.Mutex
+	stopped  bool
+	stopChan chan struct{}
+	ctx      context.Context
+	cancel   context.CancelFunc
+	clients  map[websocket.WebSocket]struct{}
+	wg       sync.WaitGroup
+}
+
+// NewTracker 创建连接跟踪器
+func NewTracker() *Tracker {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Tracker{
+		stopChan: make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+		clients:  make(map[websocket.WebSocket]struct{}),
+	}
+}
+
+// Go 在新的协程中运行 shard 的连接逻辑，停止之后不再启动新的连接，返回 false
+func (t *Tracker) Go(f func()) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.stopped {
+		return false
+	}
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		f()
+	}()
+	return true
+}
+
+// Add 记录正在监听的连接，停止之后返回 false，调用方需要自行关闭连接
+func (t *Tracker) Add(ws websocket.WebSocket) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.stopped {
+		return false
+	}
+	t.clients[ws] = struct{}{}
+	return true
+}
+
+// Remove 连接退出后移除记录
+func (t *Tracker) Remove(ws websocket.WebSocket) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	delete(t.clients, ws)
+}
+
+// Stats 获取所有正在监听的连接的运行状态，未实现 websocket.StatsProvider 的连接会被忽略
+func (t *Tracker) Stats() []websocket.Stats {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	stats := make([]websocket.Stats, 0, len(t.clients))
+	for ws := range t.clients {
+		if p, ok := ws.(websocket.StatsProvider); ok {
+			stats = append(stats, p.Stats())
+		}
+	}
+	return stats
+}
+
+// Done 停止信号
+func (t *Tracker) Done() <-chan struct{} {
+	return t.stopChan
+}
+
+// Context 返回停止时会被取消的 context，用于控制停止前的等待与请求
+func (t *Tracker) Context() context.Context {
+	return t.ctx
+}
+
+// Stopped 是否已经停止，停止后不应该再进行重连
+func (t *Tracker) Stopped() bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.stopped
+}
+
+// Sleep 等待指定时间，如果等待过程中停止了，返回 false
+func (t *Tracker) Sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-t.stopChan:
+		return false
+	}
+}
+
+// Stop 停止重连，优雅关闭所有连接，等待所有 shard 的协程退出，等待时间受 ctx 控制
+func (t *Tracker) Stop(ctx context.Context) error {
+	t.lock.Lock()
+	if !t.stopped {
+		t.stopped = true
+		close(t.stopChan)
+		t.cancel()
+	}
+	clients := make([]websocket.WebSocket, 0, len(t.clients))
+	for ws := range t.clients {
+		clients = append(clients, ws)
+	}
+	t.lock.Unlock()
+
+	for _, ws := range clients {
+		go shutdown(ctx, ws)
+	}
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func shutdown(ctx context.Context, ws websocket.WebSocket) {
+	s, ok := ws.(websocket.Shutdowner)
+	if !ok {
+		ws.Close()
+		return
+	}
+	if err := s.Shutdown(ctx); err != nil {
+		log.Errorf("%s shutdown failed, %v", ws.Session(), err)
+	}
+}
@@ -0,0 +1,59 @@
+package manager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tencent-connect/botgo/dto"
+	"github.com/tencent-connect/botgo/websocket"
+)
+
+type fakeWS struct {
+	websocket.WebSocket
+	stop chan struct{}
+}
+
+func (f *fakeWS) Listening() error {
+	<-f.stop
+	return nil
+}
+
+func (f *fakeWS) Shutdown(_ context.Context) error {
+	close(f.stop)
+	return nil
+}
+
+func (f *fakeWS) Session() *dto.Session {
+	return &dto.Session{}
+}
+
+func TestTracker_Stop(t *testing.T) {
+	tracker := NewTracker()
+	ws := &fakeWS{stop: make(chan struct{})}
+	assert.True(t, tracker.Add(ws))
+	assert.True(t, tracker.Go(func() {
+		defer tracker.Remove(ws)
+		_ = ws.Listening()
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.Nil(t, tracker.Stop(ctx))
+	assert.True(t, tracker.Stopped())
+	assert.False(t, tracker.Go(func() {}))
+	assert.False(t, tracker.Add(ws))
+	assert.False(t, tracker.Sleep(time.Minute))
+}
+
+func TestTracker_StopTimeout(t *testing.T) {
+	tracker := NewTracker()
+	block := make(chan struct{})
+	defer close(block)
+	tracker.Go(func() { <-block })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, tracker.Stop(ctx))
+}
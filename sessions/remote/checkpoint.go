@@ -0,0 +1,134 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/tencent-connect/botgo/dto"
+	"github.com/tencent-connect/botgo/log"
+	"github.com/tencent-connect/botgo/websocket"
+)
+
+const (
+	// resume 状态的key后缀，实际上的key为 `fmt.Sprintf("%s_%s", r.getShardLockKey(session), resumeStateSuffix)`
+	resumeStateSuffix = "resume"
+	// resume 状态的过期时间，超过这个时间没有更新，说明 shard 已经长时间没有连接，不再尝试 resume
+	resumeStateExpireTime = 5 * time.Minute
+	// 默认的 resume 状态保存间隔
+	defaultCheckpointInterval = 5 * time.Second
+)
+
+// resumeState 保存在 redis 中，用于其他实例接管 shard 之后进行 resume 的连接状态
+type resumeState struct {
+	ID      string `json:"id"`
+	LastSeq uint32 `json:"last_seq"`
+}
+
+// getResumeStateKey 获取 shard 的 resume 状态的key，与 shard 锁一一对应
+func (r *RedisManager) getResumeStateKey(session dto.Session) string {
+	return fmt.Sprintf("%s_%s", r.getShardLockKey(session), resumeStateSuffix)
+}
+
+// saveResumeState 保存 session 的 resume 状态，session id 为空时不保存
+func (r *RedisManager) saveResumeState(ctx context.Context, session dto.Session) error {
+	if session.ID == "" {
+		return nil
+	}
+	data, err := json.Marshal(resumeState{ID: session.ID, LastSeq: session.LastSeq})
+	if err != nil {
+		return err
+	}
+	return r.resumeStore.Set(ctx, r.getResumeStateKey(session), data, resumeStateExpireTime).Err()
+}
+
+// refreshResumeState 延长 resume 状态的过期时间，状态不存在或者续期失败时返回 false
+func (r *RedisManager) refreshResumeState(ctx context.Context, session dto.Session) bool {
+	ok, err := r.resumeStore.Expire(ctx, r.getResumeStateKey(session), resumeStateExpireTime).Result()
+	if err != nil {
+		log.Errorf("%s refresh resume state failed, err: %v", &session, err)
+	}
+	return ok
+}
+
+// loadResumeState 读取 shard 最近保存的 resume 状态，session 自身不带 session id 时，使用保存的状态进行 resume
+// 用于实例异常退出（如被 kill），session 没有被放回队列，由其他实例重新分发的场景
+func (r *RedisManager) loadResumeState(ctx context.Context, session *dto.Session) {
+	if session.ID != "" {
+		return
+	}
+	data, err := r.resumeStore.Get(ctx, r.getResumeStateKey(*session)).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			log.Errorf("[ws/session/redis] load resume state failed, err: %v", err)
+		}
+		return
+	}
+	state := &resumeState{}
+	if err = json.Unmarshal(data, state); err != nil {
+		log.Errorf("[ws/session/redis] unmarshal resume state failed, err: %v", err)
+		return
+	}
+	session.ID = state.ID
+	session.LastSeq = state.LastSeq
+	log.Infof("%s loaded resume state, last seq: %d", session, session.LastSeq)
+}
+
+// clearResumeState 清理 shard 的 resume 状态，session 不能再 resume 时调用
+func (r *RedisManager) clearResumeState(ctx context.Context, session dto.Session) {
+	if err := r.resumeStore.Del(ctx, r.getResumeStateKey(session)).Err(); err != nil {
+		log.Errorf("[ws/session/redis] clear resume state failed, err: %v", err)
+	}
+}
+
+// checkpoint 定期保存连接的 resume 状态，直到 ctx 被取消
+// session 是创建连接时使用的 session，连接的 session id 与 seq 会在读协程中更新，
+// 只有实现了 websocket.StatsProvider 的连接才能并发安全地读取，其他实现不保存
+func (r *RedisManager) checkpoint(ctx context.Context, session dto.Session, ws websocket.WebSocket) {
+	if r.checkpointInterval <= 0 {
+		return
+	}
+	if _, ok := ws.(websocket.StatsProvider); !ok {
+		return
+	}
+	ticker := time.NewTicker(r.checkpointInterval)
+	defer ticker.Stop()
+	var last resumeState
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			current := currentSession(session, ws)
+			// 状态没有变化时只续期，避免空闲的 shard 状态过期之后，接管的实例只能重新 identify
+			if current.ID == last.ID && current.LastSeq == last.LastSeq {
+				if r.refreshResumeState(ctx, current) {
+					continue
+				}
+			}
+			if err := r.saveResumeState(ctx, current); err != nil {
+				log.Errorf("%s save resume state failed, err: %v", &current, err)
+				continue
+			}
+			last = resumeState{ID: current.ID, LastSeq: current.LastSeq}
+		}
+	}
+}
+
+// currentSession 在创建连接时使用的 session 的基础上，更新连接当前的 session id 与 seq
+// 实现了 websocket.StatsProvider 的连接通过 Stats 并发安全地读取，其他实现只能在 Listening 返回之后调用
+func currentSession(session dto.Session, ws websocket.WebSocket) dto.Session {
+	if p, ok := ws.(websocket.StatsProvider); ok {
+		stats := p.Stats()
+		session.ID = stats.SessionID
+		session.LastSeq = stats.LastSeq
+		return session
+	}
+	s := ws.Session()
+	session.ID = s.ID
+	session.LastSeq = atomic.LoadUint32(&s.LastSeq)
+	return session
+}
@@ -0,0 +1,188 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tencent-connect/botgo/dto"
+	"github.com/tencent-connect/botgo/websocket"
+)
+
+// fakeRedis 内存中的 redis，只实现了 resume 状态用到的命令
+type fakeRedis struct {
+	redis.Cmdable
+	lock sync.Mutex
+	data map[string]string
+	ttl  map[string]time.Duration
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{data: make(map[string]string), ttl: make(map[string]time.Duration)}
+}
+
+func (f *fakeRedis) Set(_ context.Context, key string, value interface{}, ttl time.Duration) *redis.StatusCmd {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.data[key] = string(value.([]byte))
+	f.ttl[key] = ttl
+	return redis.NewStatusResult("OK", nil)
+}
+
+func (f *fakeRedis) Get(_ context.Context, key string) *redis.StringCmd {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	v, ok := f.data[key]
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(v, nil)
+}
+
+func (f *fakeRedis) Del(_ context.Context, keys ...string) *redis.IntCmd {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	var n int64
+	for _, key := range keys {
+		if _, ok := f.data[key]; ok {
+			delete(f.data, key)
+			delete(f.ttl, key)
+			n++
+		}
+	}
+	return redis.NewIntResult(n, nil)
+}
+
+func (f *fakeRedis) Expire(_ context.Context, key string, ttl time.Duration) *redis.BoolCmd {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if _, ok := f.data[key]; !ok {
+		return redis.NewBoolResult(false, nil)
+	}
+	f.ttl[key] = ttl
+	return redis.NewBoolResult(true, nil)
+}
+
+// expire 模拟 key 过期
+func (f *fakeRedis) expire(key string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.ttl[key] = 0
+}
+
+func (f *fakeRedis) state(key string) (resumeState, bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	v, ok := f.data[key]
+	state := resumeState{}
+	if ok {
+		_ = json.Unmarshal([]byte(v), &state)
+	}
+	return state, ok
+}
+
+// fakeStatsWebSocket 通过 Stats 提供 session 状态的连接
+type fakeStatsWebSocket struct {
+	websocket.WebSocket
+	lock  sync.Mutex
+	stats websocket.Stats
+}
+
+func (f *fakeStatsWebSocket) Stats() websocket.Stats {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.stats
+}
+
+func (f *fakeStatsWebSocket) set(id string, seq uint32) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.stats.SessionID, f.stats.LastSeq = id, seq
+}
+
+func TestResumeState(t *testing.T) {
+	store := newFakeRedis()
+	r := New(nil, WithClusterKey("cluster"))
+	r.resumeStore = store
+	session := dto.Session{Shards: dto.ShardConfig{ShardID: 1, ShardCount: 4}}
+	key := "cluster_shard_1_4_resume"
+	assert.Equal(t, key, r.getResumeStateKey(session))
+
+	// 没有 session id 时不保存
+	assert.Nil(t, r.saveResumeState(context.Background(), session))
+	_, ok := store.state(key)
+	assert.False(t, ok)
+
+	session.ID, session.LastSeq = "sid", 10
+	assert.Nil(t, r.saveResumeState(context.Background(), session))
+	state, ok := store.state(key)
+	assert.True(t, ok)
+	assert.Equal(t, resumeState{ID: "sid", LastSeq: 10}, state)
+	assert.Equal(t, resumeStateExpireTime, store.ttl[key])
+
+	// session 不带 session id 时使用保存的状态
+	loaded := dto.Session{Shards: session.Shards}
+	r.loadResumeState(context.Background(), &loaded)
+	assert.Equal(t, "sid", loaded.ID)
+	assert.Equal(t, uint32(10), loaded.LastSeq)
+
+	// session 自身带有 session id 时不覆盖
+	own := dto.Session{ID: "own", LastSeq: 1, Shards: session.Shards}
+	r.loadResumeState(context.Background(), &own)
+	assert.Equal(t, "own", own.ID)
+	assert.Equal(t, uint32(1), own.LastSeq)
+
+	r.clearResumeState(context.Background(), session)
+	_, ok = store.state(key)
+	assert.False(t, ok)
+	cleared := dto.Session{Shards: session.Shards}
+	r.loadResumeState(context.Background(), &cleared)
+	assert.Equal(t, "", cleared.ID)
+}
+
+func TestCheckpoint(t *testing.T) {
+	store := newFakeRedis()
+	r := New(nil, WithClusterKey("cluster"), WithCheckpointInterval(10*time.Millisecond))
+	r.resumeStore = store
+	session := dto.Session{Shards: dto.ShardConfig{ShardID: 0, ShardCount: 1}}
+	key := r.getResumeStateKey(session)
+	ws := &fakeStatsWebSocket{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.checkpoint(ctx, session, ws)
+		close(done)
+	}()
+	// 连接在读协程中更新 session id 与 seq，checkpoint 并发读取
+	ws.set("sid", 1)
+	assert.Eventually(t, func() bool {
+		state, _ := store.state(key)
+		return state == resumeState{ID: "sid", LastSeq: 1}
+	}, time.Second, 5*time.Millisecond)
+	ws.set("sid", 2)
+	assert.Eventually(t, func() bool {
+		state, _ := store.state(key)
+		return state.LastSeq == 2
+	}, time.Second, 5*time.Millisecond)
+	// 状态没有变化时同样续期
+	store.expire(key)
+	assert.Eventually(t, func() bool {
+		store.lock.Lock()
+		defer store.lock.Unlock()
+		return store.ttl[key] == resumeStateExpireTime
+	}, time.Second, 5*time.Millisecond)
+	// 续期时状态已经不存在，重新写入
+	r.clearResumeState(context.Background(), session)
+	assert.Eventually(t, func() bool {
+		state, _ := store.state(key)
+		return state.LastSeq == 2
+	}, time.Second, 5*time.Millisecond)
+	cancel()
+	<-done
+}
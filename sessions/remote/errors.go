@@ -11,6 +11,8 @@ var (
 	ErrSessionMarshalFailed = errors.New("session marshal failed")
 	// ErrProduceFailed 生产session失败
 	ErrProduceFailed = errors.New("produce session failed")
+	// ErrTokenSourceNotFound 无法还原 session 的 token source
+	ErrTokenSourceNotFound = errors.New("token source not found, start manager first or register a provider")
 	// ErrorNotOk redis 写失败
 	ErrorNotOk = errors.New("redis write not ok")
 )
@@ -1,5 +1,14 @@
 package remote
 
+import (
+	"time"
+
+	"github.com/tencent-connect/botgo/openapi"
+	"github.com/tencent-connect/botgo/sessions/manager"
+	"github.com/tencent-connect/botgo/websocket"
+	"golang.org/x/oauth2"
+)
+
 // Option is a function that configures a Remote.
 type Option func(manager *RedisManager)
 
@@ -9,3 +18,53 @@ func WithClusterKey(key string) Option {
 		m.clusterKey = key
 	}
 }
+
+// WithWebsocketClient 指定创建连接所使用的 websocket 实现，默认为 websocket.ClientImpl
+// 配合 client.NewWithDispatcher 使用，可以让不同的机器人使用各自的事件分发器
+func WithWebsocketClient(ws websocket.WebSocket) Option {
+	return func(m *RedisManager) {
+		m.wsClient = ws
+	}
+}
+
+// WithBackoff 指定重连的退避策略，默认为 manager.DefaultBackoff
+func WithBackoff(backoff manager.Backoff) Option {
+	return func(m *RedisManager) {
+		m.reconnector.Backoff = backoff
+	}
+}
+
+// WithShardFailingHandler 指定 shard 在本实例上连续失败超过 threshold 时的回调，可用于告警
+func WithShardFailingHandler(threshold time.Duration, handler manager.ShardFailingHandler) Option {
+	return func(m *RedisManager) {
+		m.reconnector.FailingThreshold = threshold
+		m.reconnector.OnShardFailing = handler
+	}
+}
+
+// WithWebsocketAPI 指定用于拉取 /gateway/bot 的接口，session 启动额度不足时，等待重置之后重新拉取频控信息
+// 未指定时，认为重置之后额度恢复为 Total
+func WithWebsocketAPI(api openapi.WebsocketAPI) Option {
+	return func(m *RedisManager) {
+		m.limiter = manager.NewSessionLimiter(api)
+	}
+}
+
+// WithCheckpointInterval 指定保存 resume 状态的间隔，默认 5s，小于等于 0 时不保存
+// 实例异常退出后，接管 shard 的实例可以从保存的 seq 进行 resume，避免丢失事件
+func WithCheckpointInterval(interval time.Duration) Option {
+	return func(m *RedisManager) {
+		m.checkpointInterval = interval
+	}
+}
+
+// TokenSourceProvider 根据 appID 创建 token source
+// redis 中的 session 不包含 token source，消费者使用它还原 session，适用于多个机器人共用一个集群的场景
+type TokenSourceProvider func(appID string) (oauth2.TokenSource, error)
+
+// WithTokenSourceProvider 注册 token source 的创建方法，未注册时使用 Start 传入的 token source
+func WithTokenSourceProvider(provider TokenSourceProvider) Option {
+	return func(m *RedisManager) {
+		m.tokenProvider = provider
+	}
+}
@@ -27,6 +27,8 @@ const (
 	distributeLockExpireTime = 60 * time.Second
 	// 每个不同的shard实例的分布式锁，用于避免同个 shard 被启动多个实例
 	shardLockExpireTime = 30 * time.Second
+	// 无法还原 token source 时，放回队列之前等待的时间，避免没有实例能处理的 session 在队列中空转
+	tokenSourceRetryInterval = 10 * time.Second
 )
 
 // RedisManager 基于 redis 的 session 管理器，实现分布式 websocket 监听
@@ -34,15 +36,30 @@ type RedisManager struct {
 	clusterKey         string
 	sessionQueueKey    string
 	client             *redis.Client
-	sessionProduceChan chan dto.Session // 抢到锁的服务，用于持续生产session到redis list的本地chan
+	resumeStore        redis.Cmdable           // 保存 resume 状态，默认为 client
+	sessionProduceChan chan dto.Session        // 抢到锁的服务，用于持续生产session到redis list的本地chan
+	wsClient           websocket.WebSocket     // 用于创建连接的 websocket 实现，为空时使用 websocket.ClientImpl
+	tracker            *manager.Tracker        // 跟踪正在运行的连接，用于优雅关闭
+	reconnector        *manager.Reconnector    // 按 shard 计算重连的退避时间
+	limiter            *manager.SessionLimiter // 本地维护的 session 启动额度
+	checkpointInterval time.Duration           // 保存 resume 状态的间隔，小于等于 0 时不保存
+	tokenSource        oauth2.TokenSource      // Start 时传入的 token source，用于还原从 redis 中读取的 session
+	tokenProvider      TokenSourceProvider     // 根据 appID 创建 token source，优先于 tokenSource
 }
 
 // New 创建一个新的基于 redis 的 session 管理器
 // 使用 go-redis 调用 redis，超时时间请在 NewClient 时候设置
 func New(client *redis.Client, opts ...Option) *RedisManager {
 	r := &RedisManager{
-		clusterKey: defaultClusterKey,
-		client:     client,
+		clusterKey:         defaultClusterKey,
+		client:             client,
+		tracker:            manager.NewTracker(),
+		reconnector:        &manager.Reconnector{},
+		limiter:            manager.NewSessionLimiter(nil),
+		checkpointInterval: defaultCheckpointInterval,
+	}
+	if client != nil {
+		r.resumeStore = client
 	}
 	for _, opt := range opts {
 		opt(r)
@@ -55,10 +72,17 @@ func New(client *redis.Client, opts ...Option) *RedisManager {
 // Start 启动 redis 的 session 管理器
 func (r *RedisManager) Start(apInfo *dto.WebsocketAP, tokenSource oauth2.TokenSource, intents *dto.Intent) error {
 	defer log.Sync()
-	if err := manager.CheckSessionLimit(apInfo); err != nil {
+	r.tokenSource = tokenSource
+	// 额度不足时等待重置，而不是直接退出
+	limited, err := r.limiter.Start(r.tracker.Context(), apInfo)
+	if err != nil {
+		if r.tracker.Stopped() {
+			return nil
+		}
 		log.Errorf("[ws/session/redis] session limited apInfo: %+v", apInfo)
 		return err
 	}
+	apInfo = limited
 	startInterval := manager.CalcInterval(apInfo.SessionStartLimit.MaxConcurrency)
 	log.Infof("[ws/session/redis] will start %d sessions and per session start interval is %s",
 		apInfo.Shards, startInterval)
@@ -68,7 +92,9 @@ func (r *RedisManager) Start(apInfo *dto.WebsocketAP, tokenSource oauth2.TokenSo
 
 	// 进行初始的session分发，抢锁，分发
 	// 锁60s，抢到锁的进程，需要每30s续期一次，只要自己还存活，就不能够让另外的进程抢到锁重新进行shards分发
-	ctx := context.Background()
+	// 停止时只停止续期，不主动释放锁，避免其他进程重新分发导致 session 重复
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	distributeLock := lock.New(r.clusterKey, uuid.New().String(), r.client)
 	if err := distributeLock.Lock(ctx, distributeLockExpireTime); err == nil {
 		log.Infof("[ws/session/redis] got distribute lock! i will do distributeSession, key: %s", r.clusterKey)
@@ -90,9 +116,24 @@ func (r *RedisManager) Start(apInfo *dto.WebsocketAP, tokenSource oauth2.TokenSo
 	return r.consume(startInterval)
 }
 
+// Stats 获取当前实例所有连接的运行状态，包括 shard，心跳延迟等
+func (r *RedisManager) Stats() []websocket.Stats {
+	return r.tracker.Stats()
+}
+
+// Stop 停止 redis 的 session 管理器，不再消费 session，优雅关闭本实例的所有连接
+// 连接关闭后 session 会带着 resume 信息放回 redis 中，由其他实例接管，等待所有 shard 退出后返回，等待时间受 ctx 控制
+func (r *RedisManager) Stop(ctx context.Context) error {
+	return r.tracker.Stop(ctx)
+}
+
 func (r *RedisManager) consume(startInterval time.Duration) error {
 	log.Debug("[ws/session/redis] start consume for session")
 	for {
+		if r.tracker.Stopped() {
+			log.Infof("[ws/session/redis] session manager stopped")
+			return nil
+		}
 		// brpop 返回 key value
 		data, err := r.client.BRPop(context.Background(), startInterval*2, r.sessionQueueKey).Result()
 		if err != nil {
@@ -107,15 +148,28 @@ func (r *RedisManager) consume(startInterval time.Duration) error {
 		}
 		log.Debugf("[ws/session/redis] consume data: %s", data)
 
-		session := &dto.Session{}
-		if err := json.Unmarshal([]byte(data[1]), session); err != nil {
+		record := &dto.SessionRecord{}
+		if err := json.Unmarshal([]byte(data[1]), record); err != nil {
 			// 解析出错，不放回去，直接丢弃
 			log.Errorf("[ws/session/redis] unmarshal session failed, err: %v", err)
 			continue
 		}
+		tokenSource, err := r.getTokenSource(record.AppID)
+		if err != nil {
+			// 无法还原 token source，等待之后放回去由其他实例或者下一次消费处理
+			log.Errorf("[ws/session/redis] get token source for app %s failed, err: %v", record.AppID, err)
+			r.tracker.Sleep(tokenSourceRetryInterval)
+			r.requeue(record.Session(nil))
+			continue
+		}
+		session := record.Session(tokenSource)
 
-		go r.newConnect(*session)
-		time.Sleep(startInterval) // 启动一个连接后，等待一下，避免触发服务端的并发控制
+		if !r.tracker.Go(func() { r.newConnect(session) }) {
+			// 已经停止，放回 redis 由其他实例消费
+			r.requeue(session)
+			continue
+		}
+		r.tracker.Sleep(startInterval) // 启动一个连接后，等待一下，避免触发服务端的并发控制
 	}
 }
 
@@ -137,19 +191,29 @@ func (r *RedisManager) newConnect(session dto.Session) {
 	shardLock := lock.New(r.getShardLockKey(session), uuid.NewString(), r.client)
 	if err := shardLock.Lock(ctx, shardLockExpireTime); err != nil {
 		// shard 抢锁失败，把 session 放回去，避免上一个 session 的锁释放失败，导致下一个 session 无法启动
-		r.sessionProduceChan <- session
+		r.requeue(session)
 		return
 	}
 	go shardLock.StartRenew(ctx, shardLockExpireTime)
-	// token初始化失败，重新放回去
+	// 上一个持有 shard 的实例可能异常退出了，尝试使用它保存的状态进行 resume
+	r.loadResumeState(ctx, &session)
+	// token初始化失败，退避之后重新放回去
 	if err := token.StartRefreshAccessToken(ctx, session.TokenSource); err != nil {
-		r.sessionProduceChan <- session
+		r.retry(ctx, shardLock, session, r.reconnector.Failed(session.Shards, err))
 		return
 	}
-	wsClient := websocket.ClientImpl.New(session)
+	// identify 会消耗一次启动额度，额度耗尽时在连接之前等待重置，resume 不消耗
+	if session.ID == "" {
+		if err := r.limiter.Take(r.tracker.Context()); err != nil {
+			r.retry(ctx, shardLock, session, 0)
+			return
+		}
+	}
+	wsClient := r.newClient(session)
 	if err := wsClient.Connect(); err != nil {
 		log.Error(err)
-		r.sessionProduceChan <- session // 连接失败，丢回去队列排队重连
+		// 连接失败，退避之后丢回去队列排队重连
+		r.retry(ctx, shardLock, session, r.reconnector.Failed(session.Shards, err))
 		return
 	}
 	var err error
@@ -162,15 +226,38 @@ func (r *RedisManager) newConnect(session dto.Session) {
 	}
 	if err != nil {
 		log.Errorf("[ws/session/remote] Identify/Resume err %+v", err)
+		wsClient.Close()
+		r.retry(ctx, shardLock, session, r.reconnector.Failed(session.Shards, err))
 		return
 	}
-	if err = wsClient.Listening(); err != nil {
+	connectedAt := time.Now()
+	if !r.tracker.Add(wsClient) {
+		wsClient.Close()
+		r.releaseShard(ctx, shardLock)
+		r.requeue(session)
+		return
+	}
+	defer r.tracker.Remove(wsClient)
+	// 连接退出之后先停止 checkpoint，避免清理或者保存之后又被写入旧的状态
+	checkpointCtx, stopCheckpoint := context.WithCancel(ctx)
+	checkpointDone := make(chan struct{})
+	go func() {
+		defer close(checkpointDone)
+		r.checkpoint(checkpointCtx, session, wsClient)
+	}()
+	err = wsClient.Listening()
+	stopCheckpoint()
+	<-checkpointDone
+	if err != nil {
 		log.Errorf("[ws/session/remote] Listening err %+v", err)
-		currentSession := wsClient.Session()
+		current := currentSession(session, wsClient)
 		// 对于不能够进行重连的session，需要清空 session id 与 seq
 		if manager.CanNotResume(err) {
-			currentSession.ID = ""
-			currentSession.LastSeq = 0
+			current.ID = ""
+			current.LastSeq = 0
+			r.clearResumeState(ctx, current)
+		} else if err := r.saveResumeState(ctx, current); err != nil {
+			log.Errorf("%s save resume state failed, err: %v", &current, err)
 		}
 		// 一些错误不能够鉴权，比如机器人被封禁，这里就直接退出了
 		if manager.CanNotIdentify(err) {
@@ -179,11 +266,63 @@ func (r *RedisManager) newConnect(session dto.Session) {
 			panic(msg) // 当机器人被下架，或者封禁，将不能再连接，所以 panic
 		}
 		// 将 session 放到 session chan 中，用于启动新的连接，释放锁，当前连接退出
-		shardLock.StopRenew()
-		if err = shardLock.Release(ctx); err != nil {
-			log.Errorf("[ws/session/remote] release shardLock failed, err: %s", err)
-		}
-		r.sessionProduceChan <- *currentSession
+		// 连接未能稳定运行就断开的，视为连续失败，需要退避
+		r.retry(ctx, shardLock, current,
+			r.reconnector.Disconnected(session.Shards, connectedAt, err))
 		return
 	}
 }
+
+// releaseShard 停止 shard 锁续期并释放锁，让其他连接可以接管这个 shard
+func (r *RedisManager) releaseShard(ctx context.Context, shardLock *lock.Lock) {
+	shardLock.StopRenew()
+	if err := shardLock.Release(ctx); err != nil {
+		log.Errorf("[ws/session/remote] release shardLock failed, err: %s", err)
+	}
+}
+
+// retry 释放 shard 锁，按照退避策略等待之后再放回队列重新分发
+// 等待期间不持有锁，shard 可以被其他实例接管
+func (r *RedisManager) retry(ctx context.Context, shardLock *lock.Lock, session dto.Session, delay time.Duration) {
+	r.releaseShard(ctx, shardLock)
+	if delay > 0 {
+		log.Warnf("%s will reconnect after %s", &session, delay)
+		r.tracker.Sleep(delay)
+	}
+	r.requeue(session)
+}
+
+// requeue 将 session 放回队列重新分发，manager 已经停止的情况下直接写入 redis，由其他实例接管
+// 停止时 sessionProducer 会退出，chan 可能已满，不能阻塞发送；放入 chan 之后发现已经停止时，
+// sessionProducer 可能已经清空过 chan，需要再清空一次，避免 session 丢失
+func (r *RedisManager) requeue(session dto.Session) {
+	select {
+	case r.sessionProduceChan <- session:
+		if r.tracker.Stopped() {
+			r.flushSessions()
+		}
+	case <-r.tracker.Done():
+		if err := r.produce(session); err != nil {
+			log.Errorf("[ws/session/redis] produce session on stop failed: %v", err)
+		}
+	}
+}
+
+// getTokenSource 获取 session 使用的 token source，优先使用注册的 TokenSourceProvider
+func (r *RedisManager) getTokenSource(appID string) (oauth2.TokenSource, error) {
+	if r.tokenProvider != nil {
+		return r.tokenProvider(appID)
+	}
+	if r.tokenSource == nil {
+		return nil, ErrTokenSourceNotFound
+	}
+	return r.tokenSource, nil
+}
+
+// newClient 使用指定的 websocket 实现创建连接，未指定时使用全局注册的实现
+func (r *RedisManager) newClient(session dto.Session) websocket.WebSocket {
+	if r.wsClient != nil {
+		return r.wsClient.New(session)
+	}
+	return websocket.ClientImpl.New(session)
+}
@@ -0,0 +1,73 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/tencent-connect/botgo/dto"
+	"github.com/tencent-connect/botgo/token"
+	"golang.org/x/oauth2"
+)
+
+func TestSessionRecord(t *testing.T) {
+	tokenSource := token.NewQQBotTokenSource(&token.QQBotCredentials{AppID: "123", AppSecret: "secret"})
+	session := dto.Session{
+		ID:          "session",
+		TokenSource: tokenSource,
+		LastSeq:     10,
+		Shards:      dto.ShardConfig{ShardID: 1, ShardCount: 2},
+		AppID:       getAppID(tokenSource),
+	}
+	data, err := json.Marshal(session.Record())
+	assert.Nil(t, err)
+
+	record := &dto.SessionRecord{}
+	assert.Nil(t, json.Unmarshal(data, record))
+	assert.Equal(t, "123", record.AppID)
+
+	r := New(nil, WithTokenSourceProvider(func(appID string) (oauth2.TokenSource, error) {
+		if appID != "123" {
+			return nil, errors.New("unknown app")
+		}
+		return tokenSource, nil
+	}))
+	ts, err := r.getTokenSource(record.AppID)
+	assert.Nil(t, err)
+	assert.Equal(t, session, record.Session(ts))
+
+	_, err = r.getTokenSource("456")
+	assert.NotNil(t, err)
+	// 未注册 provider 且未启动时，无法还原
+	_, err = New(nil).getTokenSource("123")
+	assert.Equal(t, ErrTokenSourceNotFound, err)
+}
+
+func TestRequeue_Stopped(t *testing.T) {
+	// 不可用的 redis，写入失败只打印日志
+	r := New(redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1}))
+	assert.Nil(t, r.tracker.Stop(context.Background()))
+
+	// chan 已满并且 sessionProducer 已经退出时，不能阻塞
+	r.sessionProduceChan = make(chan dto.Session, 1)
+	r.sessionProduceChan <- dto.Session{}
+	done := make(chan struct{})
+	go func() {
+		r.requeue(dto.Session{ID: "full"})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("requeue blocked after stop")
+	}
+
+	// 放入 chan 之后发现已经停止，需要清空 chan，不能留在 chan 中丢失
+	r.sessionProduceChan = make(chan dto.Session, 1)
+	r.requeue(dto.Session{ID: "buffered"})
+	assert.Equal(t, 0, len(r.sessionProduceChan))
+}
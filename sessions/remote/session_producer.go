@@ -17,12 +17,14 @@ func (r *RedisManager) distributeSession(
 	if err := r.client.Del(context.Background(), r.sessionQueueKey); err != nil {
 		log.Errorf("[ws/session/redis] clear session list failed: %v", err)
 	}
+	appID := getAppID(tokenSource)
 	for i := uint32(0); i < apInfo.Shards; i++ {
 		session := dto.Session{
 			URL:         apInfo.URL,
 			TokenSource: tokenSource,
 			Intent:      *intents,
 			LastSeq:     0,
+			AppID:       appID,
 			Shards: dto.ShardConfig{
 				ShardID:    i,
 				ShardCount: apInfo.Shards,
@@ -35,21 +37,51 @@ func (r *RedisManager) distributeSession(
 }
 
 // sessionProducer 从 chan 取到session，push 到 redis，push 失败放回 chan
+// manager 停止后，不再等待间隔，把 chan 中剩余的 session 直接写入 redis
 func (r *RedisManager) sessionProducer(startInterval time.Duration) {
-	for session := range r.sessionProduceChan {
-		time.Sleep(startInterval) // 每次生产需要等待一个间隔，控制消费者连接并发
-		if err := r.produce(session); err != nil {
-			log.Errorf("[ws/session/redis] produce session failed: %v", err)
-			r.sessionProduceChan <- session // 放回去重试
+	for {
+		select {
+		case <-r.tracker.Done():
+			r.flushSessions()
+			return
+		case session := <-r.sessionProduceChan:
+			r.tracker.Sleep(startInterval) // 每次生产需要等待一个间隔，控制消费者连接并发
+			if err := r.produce(session); err != nil {
+				log.Errorf("[ws/session/redis] produce session failed: %v", err)
+				r.sessionProduceChan <- session // 放回去重试
+			}
+		}
+	}
+}
+
+// flushSessions 将 chan 中剩余的 session 写入 redis
+func (r *RedisManager) flushSessions() {
+	for {
+		select {
+		case session := <-r.sessionProduceChan:
+			if err := r.produce(session); err != nil {
+				log.Errorf("[ws/session/redis] produce session on stop failed: %v", err)
+			}
+		default:
+			return
 		}
 	}
 }
 
 func (r *RedisManager) produce(session dto.Session) error {
-	data, err := json.Marshal(session)
+	// TokenSource 无法序列化，只写入 session 的描述，消费时重新创建
+	data, err := json.Marshal(session.Record())
 	log.Debugf("[ws][session/redis] produce session data is %s", string(data))
 	if err != nil {
 		return ErrSessionMarshalFailed
 	}
 	return r.client.LPush(context.Background(), r.sessionQueueKey, data).Err()
 }
+
+// getAppID 从 token source 中获取 appID，用于消费时通过 TokenSourceProvider 还原 token source
+func getAppID(tokenSource oauth2.TokenSource) string {
+	if s, ok := tokenSource.(interface{ GetAppID() string }); ok {
+		return s.GetAppID()
+	}
+	return ""
+}
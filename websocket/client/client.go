@@ -2,10 +2,13 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"os/signal"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -13,6 +16,7 @@ import (
 	"github.com/tencent-connect/botgo/dto"
 	"github.com/tencent-connect/botgo/errs"
 	"github.com/tencent-connect/botgo/event"
+	"github.com/tencent-connect/botgo/event/pool"
 	"github.com/tencent-connect/botgo/log"
 	"github.com/tencent-connect/botgo/websocket"
 )
@@ -25,13 +29,42 @@ func Setup() {
 	websocket.Register(&Client{})
 }
 
+// NewClient 创建带有配置的 client，通过它 New 出来的连接都会沿用这些配置
+// 可以通过 session manager 的 option 传入，或者使用 websocket.Register 注册为默认实现
+func NewClient(opts ...Option) *Client {
+	c := &Client{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// NewWithDispatcher 创建使用指定事件分发器的 client，通过它 New 出来的连接都会把事件投递到该分发器
+// 可以通过 session manager 的 option 传入，用于在同一进程中运行多个机器人
+func NewWithDispatcher(dispatcher *event.Dispatcher) *Client {
+	return NewClient(WithDispatcher(dispatcher))
+}
+
 // New 新建一个连接对象
 func (c *Client) New(session dto.Session) websocket.WebSocket {
+	dispatcher := c.dispatcher
+	if dispatcher == nil {
+		dispatcher = event.DefaultDispatcher
+	}
+	// 连接级别的 context，连接关闭时 cancel，传递给携带 context 的 handler
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Client{
 		messageQueue:    make(messageChan, DefaultQueueSize),
 		session:         &session,
+		dispatcher:      dispatcher,
+		pool:            c.pool,
+		ctx:             ctx,
+		cancel:          cancel,
 		closeChan:       make(closeErrorChan, 10),
 		heartBeatTicker: time.NewTicker(60 * time.Second), // 先给一个默认 ticker，在收到 hello 包之后，会 reset
+		shutdownChan:    make(chan struct{}),
+		listenDone:      make(chan struct{}),
+		handleDone:      make(chan struct{}),
 	}
 }
 
@@ -41,9 +74,22 @@ type Client struct {
 	conn            *wss.Conn
 	messageQueue    messageChan
 	session         *dto.Session
+	sessionLock     sync.RWMutex // 保护 ready 事件中更新的 session ID 与 shard 信息，LastSeq 使用原子操作
 	user            *dto.WSUser
+	dispatcher      *event.Dispatcher // 事件分发器，默认为 event.DefaultDispatcher
+	pool            *pool.Pool        // 事件处理协程池，为空时在 listenMessageAndHandle 中顺序处理
+	ctx             context.Context
+	cancel          context.CancelFunc
 	closeChan       closeErrorChan
 	heartBeatTicker *time.Ticker // 用于维持定时心跳
+	heartbeat       heartbeat    // 心跳状态
+
+	shutdownOnce sync.Once
+	shutdownCtx  context.Context // 优雅关闭的 context，用于控制等待事件处理完成的超时时间
+	shutdownChan chan struct{}   // 优雅关闭信号
+	listenDone   chan struct{}   // Listening 退出后关闭
+	handleDone   chan struct{}   // 事件队列处理完成后关闭
+	inflight     sync.WaitGroup  // 已经投递到协程池，但是还没有处理完成的事件
 }
 
 type messageChan chan *dto.WSPayload
@@ -69,6 +115,7 @@ func (c *Client) Connect() error {
 // Listening 开始监听，会阻塞进程，内部会从事件队列不断的读取事件，解析后投递到注册的 event handler，如果读取消息过程中发生错误，会循环
 // 定时心跳也在这里维护
 func (c *Client) Listening() error {
+	defer close(c.listenDone)
 	defer c.Close()
 	// reading message
 	go c.readMessageToQueue()
@@ -87,12 +134,16 @@ func (c *Client) Listening() error {
 		case <-resumeSignal: // 使用信号量控制连接立即重连
 			log.Infof("%s, received resumeSignal signal", c.session)
 			return errs.ErrNeedReConnect
+		case <-c.shutdownChan:
+			log.Infof("%s, received shutdown signal", c.session)
+			c.gracefulClose(c.shutdownCtx)
+			return errs.ErrConnShutdown
 		case err := <-c.closeChan:
 			// 关闭连接的错误码 https://bot.q.qq.com/wiki/develop/api/gateway/error/error.html
 			log.Errorf("%s Listening stop. err is %v", c.session, err)
 			// 不能够 identify 的错误
 			if wss.IsCloseError(err, errs.WSCodeBackendBotOffline, errs.WSCodeBackendBotBanned) {
-				err = errs.New(errs.CodeConnCloseCantIdentify, err.Error())
+				err = errs.Wrap(errs.CodeConnCloseCantIdentify, err.Error(), err)
 			}
 			// accessToken过期
 			if wss.IsCloseError(err, errs.WSCodeBackendAuthenticationFail) {
@@ -101,20 +152,23 @@ func (c *Client) Listening() error {
 			// 这里用 UnexpectedCloseError，如果有需要排除在外的 close error code，可以补充在第二个参数上
 			// 4009: session time out, 发了 reconnect 之后马上关闭连接时候的错误码，这个是允许 resumeSignal 的
 			if wss.IsUnexpectedCloseError(err, errs.WSCodeBackendSessionTimeOut) {
-				err = errs.New(errs.CodeConnCloseCantResume, err.Error())
-			}
-			if event.DefaultHandlers.ErrorNotify != nil {
-				// 通知到使用方错误
-				event.DefaultHandlers.ErrorNotify(err)
+				err = errs.Wrap(errs.CodeConnCloseCantResume, err.Error(), err)
 			}
+			c.notifyError(err)
 			return err
 		case <-c.heartBeatTicker.C:
 			log.Debugf("%s listened heartBeat", c.session)
+			// 上一次的心跳没有收到 ack，认为连接已经僵死，关闭连接交给 session manager 进行 resume
+			if !c.heartbeat.send(time.Now()) {
+				log.Errorf("%s heartbeat ack timeout, close connection", c.session)
+				c.notifyError(errs.ErrHeartbeatTimeout)
+				return errs.ErrHeartbeatTimeout
+			}
 			heartBeatEvent := &dto.WSPayload{
 				WSPayloadBase: dto.WSPayloadBase{
 					OPCode: dto.WSHeartbeat,
 				},
-				Data: c.session.LastSeq,
+				Data: atomic.LoadUint32(&c.session.LastSeq),
 			}
 			// 不处理错误，Write 内部会处理，如果发生发包异常，会通知主协程退出
 			_ = c.Write(heartBeatEvent)
@@ -178,8 +232,57 @@ func (c *Client) Identify() error {
 	return c.Write(payload)
 }
 
+// Shutdown 优雅关闭连接，使用正常关闭码关闭 websocket，停止接收新事件，等待已经收到的事件处理完成后返回
+// 等待时间受 ctx 控制，超时后强制关闭连接并返回 ctx 的错误
+func (c *Client) Shutdown(ctx context.Context) error {
+	c.shutdownOnce.Do(func() {
+		c.shutdownCtx = ctx
+		close(c.shutdownChan)
+	})
+	select {
+	case <-c.listenDone:
+		return nil
+	case <-ctx.Done():
+		if c.conn != nil {
+			_ = c.conn.Close()
+		}
+		return ctx.Err()
+	}
+}
+
+// gracefulClose 发送正常关闭帧，等待服务端关闭连接后，事件队列与协程池中的事件处理完成
+func (c *Client) gracefulClose(ctx context.Context) {
+	c.heartBeatTicker.Stop()
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(time.Second)
+	}
+	closeMessage := wss.FormatCloseMessage(wss.CloseNormalClosure, "shutdown")
+	if err := c.conn.WriteControl(wss.CloseMessage, closeMessage, deadline); err != nil {
+		log.Errorf("%s write close message failed, %v", c.session, err)
+		_ = c.conn.Close() // 关闭帧发送失败，直接关闭连接，让读协程退出
+	}
+	select {
+	case <-c.handleDone:
+	case <-ctx.Done():
+		log.Warnf("%s wait message queue handled timeout, %v", c.session, ctx.Err())
+		return
+	}
+	inflightDone := make(chan struct{})
+	go func() {
+		c.inflight.Wait()
+		close(inflightDone)
+	}()
+	select {
+	case <-inflightDone:
+	case <-ctx.Done():
+		log.Warnf("%s wait inflight events handled timeout, %v", c.session, ctx.Err())
+	}
+}
+
 // Close 关闭连接
 func (c *Client) Close() {
+	c.cancel()
 	if err := c.conn.Close(); err != nil {
 		log.Errorf("%s, close conn err: %v", c.session, err)
 	}
@@ -191,6 +294,23 @@ func (c *Client) Session() *dto.Session {
 	return c.session
 }
 
+// Stats 获取连接的运行状态，包括心跳延迟，事件队列长度等
+func (c *Client) Stats() websocket.Stats {
+	sentAt, ackAt, latency := c.heartbeat.snapshot()
+	c.sessionLock.RLock()
+	sessionID, shards := c.session.ID, c.session.Shards
+	c.sessionLock.RUnlock()
+	return websocket.Stats{
+		SessionID:        sessionID,
+		Shards:           shards,
+		LastSeq:          atomic.LoadUint32(&c.session.LastSeq),
+		LastHeartbeat:    sentAt,
+		LastHeartbeatAck: ackAt,
+		HeartbeatLatency: latency,
+		QueueDepth:       len(c.messageQueue),
+	}
+}
+
 func (c *Client) readMessageToQueue() {
 	for {
 		_, message, err := c.conn.ReadMessage()
@@ -221,6 +341,7 @@ func (c *Client) readMessageToQueue() {
 }
 
 func (c *Client) listenMessageAndHandle() {
+	defer close(c.handleDone)
 	defer func() {
 		// panic，一般是由于业务自己实现的 handle 不完善导致
 		// 打印日志后，关闭这个连接，进入重连流程
@@ -236,17 +357,48 @@ func (c *Client) listenMessageAndHandle() {
 			c.readyHandler(payload)
 			continue
 		}
-		// 解析具体事件，并投递给业务注册的 handler
-		if err := event.ParseAndHandle(payload); err != nil {
-			log.Errorf("%s parseAndHandle failed, %v", c.session, err)
+		if c.pool != nil {
+			c.submit(payload)
+			continue
 		}
+		c.handle(payload)
 	}
 	log.Infof("%s message queue is closed", c.session)
 }
 
+// handle 解析具体事件，并投递给业务注册的 handler
+func (c *Client) handle(payload *dto.WSPayload) {
+	if err := c.dispatcher.ParseAndHandleContext(c.ctx, payload); err != nil {
+		log.Errorf("%s parseAndHandle failed, %v", c.session, err)
+	}
+}
+
+// submit 将事件投递到协程池中处理，协程池中的 panic 只打印日志，不会关闭连接
+// 事件被 pool.OverflowDropOldest 丢弃时同样需要结束 inflight 计数，否则 gracefulClose 会一直等待到超时
+func (c *Client) submit(payload *dto.WSPayload) {
+	c.inflight.Add(1)
+	err := c.pool.SubmitWithDrop(c.pool.Key(payload), func() {
+		defer c.inflight.Done()
+		defer func() {
+			if err := recover(); err != nil {
+				websocket.PanicHandler(err, c.session)
+			}
+		}()
+		c.handle(payload)
+	}, func() {
+		c.inflight.Done()
+		log.Warnf("%s event dropped by pool, type: %s, id: %s", c.session, payload.Type, payload.EventID)
+	})
+	if err != nil {
+		c.inflight.Done()
+		log.Errorf("%s submit event to pool failed, %v, type: %s, id: %s",
+			c.session, err, payload.Type, payload.EventID)
+	}
+}
+
 func (c *Client) saveSeq(seq uint32) {
 	if seq > 0 {
-		c.session.LastSeq = seq
+		atomic.StoreUint32(&c.session.LastSeq, seq) // 会被心跳与 Stats 并发读取
 	}
 }
 
@@ -256,7 +408,8 @@ func (c *Client) isHandleBuildIn(payload *dto.WSPayload) bool {
 	switch payload.OPCode {
 	case dto.WSHello: // 接收到 hello 后需要开始发心跳
 		c.startHeartBeatTicker(payload.RawMessage)
-	case dto.WSHeartbeatAck: // 心跳 ack 不需要业务处理
+	case dto.WSHeartbeatAck: // 心跳 ack 不需要业务处理，记录 ack 时间用于计算延迟与检测僵死连接
+		c.heartbeat.ack(time.Now())
 	case dto.WSReconnect: // 达到连接时长，需要重新连接，此时可以通过 resume 续传原连接上的事件
 		c.closeChan <- errs.ErrNeedReConnect
 	case dto.WSInvalidSession: // 无效的 sessionLog，需要重新鉴权
@@ -284,17 +437,32 @@ func (c *Client) readyHandler(payload *dto.WSPayload) {
 		log.Errorf("%s parseReadyData failed, %v, message %v", c.session, err, payload.RawMessage)
 	}
 	c.version = readyData.Version
-	// 基于 ready 事件，更新 session 信息
+	// 基于 ready 事件，更新 session 信息，会被 Stats 并发读取
+	c.sessionLock.Lock()
 	c.session.ID = readyData.SessionID
 	c.session.Shards.ShardID = readyData.Shard[0]
 	c.session.Shards.ShardCount = readyData.Shard[1]
+	c.sessionLock.Unlock()
 	c.user = &dto.WSUser{
 		ID:       readyData.User.ID,
 		Username: readyData.User.Username,
 		Bot:      readyData.User.Bot,
 	}
 	// 调用自定义的 ready 回调
-	if event.DefaultHandlers.Ready != nil {
-		event.DefaultHandlers.Ready(payload, readyData)
+	handlers := c.dispatcher.Handlers()
+	if handlers.ReadyCtx != nil {
+		handlers.ReadyCtx(event.NewContext(c.ctx, payload), payload, readyData)
+	} else if handlers.Ready != nil {
+		handlers.Ready(payload, readyData)
+	}
+}
+
+// notifyError 通知到使用方错误
+func (c *Client) notifyError(err error) {
+	handlers := c.dispatcher.Handlers()
+	if handlers.ErrorNotifyCtx != nil {
+		handlers.ErrorNotifyCtx(event.NewContext(c.ctx, &dto.WSPayload{Session: c.session}), err)
+	} else if handlers.ErrorNotify != nil {
+		handlers.ErrorNotify(err)
 	}
 }
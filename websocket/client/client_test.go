@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	wss "github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tencent-connect/botgo/dto"
+	"github.com/tencent-connect/botgo/event"
+	"github.com/tencent-connect/botgo/event/pool"
+)
+
+func TestClient_ShutdownWithDroppedEvents(t *testing.T) {
+	const events = 5
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&wss.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for i := 1; i <= events; i++ {
+			msg := fmt.Sprintf(`{"op":0,"s":%d,"t":"TEST_EVENT","id":"%d","d":{}}`, i, i)
+			if err := conn.WriteMessage(wss.TextMessage, []byte(msg)); err != nil {
+				return
+			}
+		}
+		// 读到关闭帧之后，默认的 close handler 会回复关闭帧
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handled := 0
+	dispatcher := event.NewDispatcher()
+	dispatcher.RegisterHandler(dto.WSDispatchEvent, "TEST_EVENT", func(_ *dto.WSPayload, _ []byte) error {
+		handled++
+		if handled == 1 {
+			close(started)
+			<-release
+		}
+		return nil
+	})
+	p := pool.New(pool.Config{Workers: 1, QueueSize: 1, Overflow: pool.OverflowDropOldest})
+	defer p.Close()
+
+	c := NewClient(WithDispatcher(dispatcher), WithWorkerPool(p)).
+		New(dto.Session{URL: "ws" + strings.TrimPrefix(srv.URL, "http")}).(*Client)
+	assert.Nil(t, c.Connect())
+	go func() { _ = c.Listening() }()
+
+	<-started
+	// 第一个事件阻塞 worker，后续事件只保留最后一个，其余被丢弃
+	assert.Eventually(t, func() bool {
+		return p.Stats().Dropped == events-2
+	}, time.Second, 10*time.Millisecond)
+	close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	start := time.Now()
+	assert.Nil(t, c.Shutdown(ctx))
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+	assert.Equal(t, 2, handled)
+}
+
+func TestClient_StatsConcurrentWithReady(t *testing.T) {
+	c := NewClient(WithDispatcher(event.NewDispatcher())).New(dto.Session{}).(*Client)
+	payload := &dto.WSPayload{
+		RawMessage: []byte(`{"op":0,"t":"READY","d":{"session_id":"sid","shard":[1,2],"user":{}}}`),
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.readyHandler(payload)
+		c.saveSeq(3)
+	}()
+	_ = c.Stats()
+	<-done
+	stats := c.Stats()
+	assert.Equal(t, "sid", stats.SessionID)
+	assert.Equal(t, dto.ShardConfig{ShardID: 1, ShardCount: 2}, stats.Shards)
+	assert.Equal(t, uint32(3), stats.LastSeq)
+}
@@ -0,0 +1,44 @@
+package client
+
+import (
+	"sync"
+	"time"
+)
+
+// heartbeat 心跳状态，记录心跳发送与 ack 的时间，用于计算延迟，检测半开的僵死连接
+type heartbeat struct {
+	lock    sync.RWMutex
+	sentAt  time.Time     // 最近一次发送心跳的时间
+	ackAt   time.Time     // 最近一次收到心跳 ack 的时间
+	latency time.Duration // 最近一次心跳的往返延迟
+	pending bool          // 已经发送心跳，还没有收到 ack
+}
+
+// send 记录发送心跳，如果上一次发送的心跳还没有收到 ack，返回 false
+func (h *heartbeat) send(now time.Time) bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.pending {
+		return false
+	}
+	h.sentAt = now
+	h.pending = true
+	return true
+}
+
+// ack 记录收到心跳 ack，计算往返延迟
+func (h *heartbeat) ack(now time.Time) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.ackAt = now
+	if h.pending {
+		h.latency = now.Sub(h.sentAt)
+		h.pending = false
+	}
+}
+
+func (h *heartbeat) snapshot() (sentAt, ackAt time.Time, latency time.Duration) {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	return h.sentAt, h.ackAt, h.latency
+}
@@ -0,0 +1,22 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeartbeat(t *testing.T) {
+	h := &heartbeat{}
+	now := time.Now()
+	assert.True(t, h.send(now))
+	h.ack(now.Add(30 * time.Millisecond))
+	_, ackAt, latency := h.snapshot()
+	assert.Equal(t, 30*time.Millisecond, latency)
+	assert.Equal(t, now.Add(30*time.Millisecond), ackAt)
+
+	// 上一次心跳没有收到 ack，视为超时
+	assert.True(t, h.send(now.Add(time.Second)))
+	assert.False(t, h.send(now.Add(2*time.Second)))
+}
@@ -0,0 +1,25 @@
+package client
+
+import (
+	"github.com/tencent-connect/botgo/event"
+	"github.com/tencent-connect/botgo/event/pool"
+)
+
+// Option client 配置项
+type Option func(*Client)
+
+// WithDispatcher 指定事件分发器，默认为 event.DefaultDispatcher
+func WithDispatcher(dispatcher *event.Dispatcher) Option {
+	return func(c *Client) {
+		c.dispatcher = dispatcher
+	}
+}
+
+// WithWorkerPool 使用协程池并发处理事件，默认在单个协程中顺序处理
+// 事件按照 pool 的 KeyFunc 计算 key，相同 key 的事件保序，不同会话之间并发执行
+// pool 可以被同一个机器人的多个 shard 共享，由使用方负责关闭，通过 pool.Stats 获取队列深度等统计信息
+func WithWorkerPool(p *pool.Pool) Option {
+	return func(c *Client) {
+		c.pool = p
+	}
+}
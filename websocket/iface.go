@@ -1,6 +1,9 @@
 package websocket
 
 import (
+	"context"
+	"time"
+
 	"github.com/tencent-connect/botgo/dto"
 )
 
@@ -23,3 +26,25 @@ type WebSocket interface {
 	// Close 关闭连接
 	Close()
 }
+
+// Shutdowner 支持优雅关闭的 websocket 实现，session manager 停止时，未实现该接口的连接会直接调用 Close 关闭
+type Shutdowner interface {
+	// Shutdown 使用正常关闭码关闭连接，等待已经收到的事件处理完成，Listening 返回后再返回
+	Shutdown(ctx context.Context) error
+}
+
+// Stats 连接的运行状态
+type Stats struct {
+	SessionID        string
+	Shards           dto.ShardConfig
+	LastSeq          uint32
+	LastHeartbeat    time.Time     // 最近一次发送心跳的时间
+	LastHeartbeatAck time.Time     // 最近一次收到心跳 ack 的时间
+	HeartbeatLatency time.Duration // 最近一次心跳的往返延迟
+	QueueDepth       int           // 待处理的事件数
+}
+
+// StatsProvider 可以提供运行状态的 websocket 实现
+type StatsProvider interface {
+	Stats() Stats
+}